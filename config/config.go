@@ -3,7 +3,9 @@ package config
 import (
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -16,6 +18,52 @@ type Config struct {
 	RabbitMQURL     string
 	ProductQueue    string
 	ProductExchange string
+	IdempotencyTTL  time.Duration
+	// 幂等键在请求处理期间的占用时长，超时后视为处理中断，允许使用同一个键重试
+	IdempotencyLease time.Duration
+	// 软删除商品保留天数，超过后自动永久删除；默认 0 表示不自动清理
+	TrashRetentionDays int
+	TrashPurgeInterval time.Duration
+	// 批量导入
+	ImportBatchSize      int
+	ImportEventMode      string
+	ImportMaxUploadBytes int64
+	// 商品推广 Feed
+	FeedTitle           string
+	FeedSiteURL         string
+	FeedProductURL      string
+	FeedImageBaseURL    string
+	FeedCurrency        string
+	FeedFieldMap        string
+	FeedRefreshInterval time.Duration
+	// 全文搜索：mysql 使用 FULLTEXT 索引，memory 使用进程内倒排索引。
+	// mysql 忽略少于 3 个字符的词（所有词都较短时改用 LIKE 匹配），被忽略的词在列表响应的 ignored_terms 中返回
+	SearchBackend string
+	// 单次搜索最多命中的商品数，超出部分不参与列表和总数，列表响应中 search_truncated 为 true
+	SearchMaxResults int
+	// 同义词和停用词的定期重新加载间隔，作为变更事件丢失时的兜底
+	SearchDictionaryRefresh time.Duration
+	// 价格分面的区间边界，逗号分隔且递增
+	PriceFacetBuckets string
+	// 批量获取商品单次最多的ID数量
+	BatchGetMaxIDs int
+	// 商品价格的默认币种
+	DefaultCurrency string
+	// 汇率的定期重新加载间隔，作为变更事件丢失时的兜底
+	ExchangeRateRefresh time.Duration
+	// 汇率换算的舍入规则，如 "half_up,JPY=up,KRW=down"，第一项为默认规则
+	CurrencyRounding string
+	// 价格计划的检查间隔
+	PriceScheduleInterval time.Duration
+	// 促销状态的检查间隔，同时定期重新加载促销规则
+	PromotionCheckInterval time.Duration
+	// 定时发布商品的检查间隔
+	ProductPublishInterval time.Duration
+	// SKU 格式（正则表达式）
+	SKUPattern string
+	// 新建商品未指定 SKU 时按模板自动生成，模板支持 {prefix}、{category_id}、{seq}、{seq:N}
+	SKUAutoGenerate    bool
+	SKUGeneratePattern string
 }
 
 func LoadConfig() *Config {
@@ -29,6 +77,43 @@ func LoadConfig() *Config {
 		RabbitMQURL:     getEnv("RABBITMQ_URL", "amqp://admin:rabbitmq@IP:5672/"),
 		ProductQueue:    getEnv("PRODUCT_QUEUE", "product_events"),
 		ProductExchange: getEnv("PRODUCT_EXCHANGE", "product_exchange"),
+		IdempotencyTTL:  getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		IdempotencyLease: getEnvDuration("IDEMPOTENCY_LEASE", time.Minute),
+
+		TrashRetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 0),
+		TrashPurgeInterval: getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour),
+
+		ImportBatchSize:      getEnvInt("IMPORT_BATCH_SIZE", 100),
+		ImportEventMode:      getEnv("IMPORT_EVENT_MODE", "per_product"),
+		ImportMaxUploadBytes: int64(getEnvInt("IMPORT_MAX_UPLOAD_MB", 50)) << 20,
+
+		FeedTitle:           getEnv("FEED_TITLE", "Product Feed"),
+		FeedSiteURL:         getEnv("FEED_SITE_URL", "https://shop.example.com"),
+		FeedProductURL:      getEnv("FEED_PRODUCT_URL", "https://shop.example.com/products/{id}"),
+		FeedImageBaseURL:    getEnv("FEED_IMAGE_BASE_URL", ""),
+		FeedCurrency:        getEnv("FEED_CURRENCY", "CNY"),
+		FeedFieldMap:        getEnv("FEED_FIELD_MAP", "brand=attr:brand,mpn=sku"),
+		FeedRefreshInterval: getEnvDuration("FEED_REFRESH_INTERVAL", time.Hour),
+
+		SearchBackend:           getEnv("SEARCH_BACKEND", "mysql"),
+		SearchMaxResults:        getEnvInt("SEARCH_MAX_RESULTS", 1000),
+		SearchDictionaryRefresh: getEnvDuration("SEARCH_DICTIONARY_REFRESH", time.Minute),
+
+		PriceFacetBuckets: getEnv("PRICE_FACET_BUCKETS", "0,50,100,200,500,1000"),
+		BatchGetMaxIDs:    getEnvInt("BATCH_GET_MAX_IDS", 300),
+
+		DefaultCurrency:     strings.ToUpper(getEnv("DEFAULT_CURRENCY", "CNY")),
+		ExchangeRateRefresh: getEnvDuration("EXCHANGE_RATE_REFRESH", 5*time.Minute),
+		CurrencyRounding:    getEnv("CURRENCY_ROUNDING", "half_up"),
+
+		PriceScheduleInterval:  getEnvDuration("PRICE_SCHEDULE_INTERVAL", 30*time.Second),
+		PromotionCheckInterval: getEnvDuration("PROMOTION_CHECK_INTERVAL", 30*time.Second),
+		ProductPublishInterval: getEnvDuration("PRODUCT_PUBLISH_INTERVAL", 30*time.Second),
+
+		SKUPattern:         getEnv("SKU_PATTERN", `^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`),
+		SKUAutoGenerate:    getEnvBool("SKU_AUTO_GENERATE", false),
+		SKUGeneratePattern: getEnv("SKU_GENERATE_PATTERN", "{prefix}-{seq:6}"),
 	}
 }
 
@@ -39,6 +124,33 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 func getEnvFromFile(fileKey, envKey, defaultValue string) string {
 	if filePath := os.Getenv(fileKey); filePath != "" {
 		if content, err := ioutil.ReadFile(filePath); err == nil {
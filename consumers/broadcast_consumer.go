@@ -0,0 +1,62 @@
+package consumers
+
+import (
+	"encoding/json"
+	"log"
+	"product-service/models"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// EventHandler 处理本副本收到的商品事件，用于刷新进程内缓存
+type EventHandler func(event models.ProductEvent)
+
+var (
+	handlersMu sync.RWMutex
+	handlers   []EventHandler
+)
+
+// RegisterEventHandler 注册广播事件处理函数，需在 StartBroadcastConsumer 之前调用
+func RegisterEventHandler(handler EventHandler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers = append(handlers, handler)
+}
+
+// Dispatch 将事件分发给所有已注册的处理函数
+func Dispatch(event models.ProductEvent) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// StartBroadcastConsumer 消费当前副本专属队列中的事件
+func StartBroadcastConsumer(ch *amqp.Channel, queue string) {
+	msgs, err := ch.Consume(
+		queue,
+		"",    // consumers tag
+		true,  // auto-ack
+		true,  // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,
+	)
+	if err != nil {
+		log.Printf("Failed to register broadcast consumer: %v", err)
+		return
+	}
+
+	go func() {
+		for msg := range msgs {
+			var event models.ProductEvent
+			if err := json.Unmarshal(msg.Body, &event); err != nil {
+				log.Printf("Failed to unmarshal broadcast event: %v", err)
+				continue
+			}
+			Dispatch(event)
+		}
+	}()
+}
@@ -5,10 +5,24 @@ import (
 	"log"
 	"product-service/config"
 	"product-service/models"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// RatesHandler 保存 rates_updated 消息中的汇率，返回错误时消息重新入队
+type RatesHandler func(event models.ProductEvent) error
+
+// 处理失败的消息重新入队前等待的时间，避免持续失败时反复投递
+const requeueDelay = 5 * time.Second
+
+var ratesHandler RatesHandler
+
+// SetRatesHandler 设置汇率更新消息的处理函数，需在 StartProductConsumer 之前调用
+func SetRatesHandler(handler RatesHandler) {
+	ratesHandler = handler
+}
+
 func StartProductConsumer(ch *amqp.Channel, cfg *config.Config) {
 	msgs, err := ch.Consume(
 		cfg.ProductQueue,
@@ -26,17 +40,22 @@ func StartProductConsumer(ch *amqp.Channel, cfg *config.Config) {
 
 	go func() {
 		for msg := range msgs {
-			processProductMessage(msg)
+			if err := processProductMessage(msg); err != nil {
+				time.Sleep(requeueDelay)
+				msg.Nack(false, true) // 处理失败，重新入队
+				continue
+			}
 			msg.Ack(false) // 手动确认消息
 		}
 	}()
 }
 
-func processProductMessage(msg amqp.Delivery) {
+// processProductMessage 处理一条商品事件，返回错误表示需要重新入队
+func processProductMessage(msg amqp.Delivery) error {
 	var event models.ProductEvent
 	if err := json.Unmarshal(msg.Body, &event); err != nil {
 		log.Printf("Failed to unmarshal event: %v", err)
-		return
+		return nil
 	}
 
 	switch event.EventType {
@@ -50,10 +69,32 @@ func processProductMessage(msg amqp.Delivery) {
 		log.Printf("New category created: %d", event.CategoryID)
 	case models.EventImageAdded:
 		log.Printf("Image added to product %d: %s", event.ProductID, event.ImageData.ImageURL)
+	case models.EventImageUpdated:
+		log.Printf("Image %d of product %d updated: %s", event.ImageData.ID, event.ProductID, event.ImageData.ImageURL)
+	case models.EventImageRemoved:
+		log.Printf("Image %d removed from product %d", event.ImageData.ID, event.ProductID)
 	case models.EventAttributeAdded:
 		log.Printf("Attribute added to product %d: %s=%s",
 			event.ProductID, event.Attribute.Name, event.Attribute.Value)
+	case models.EventAttributeUpdated:
+		log.Printf("Attribute %d of product %d updated: %s=%s",
+			event.Attribute.ID, event.ProductID, event.Attribute.Name, event.Attribute.Value)
+	case models.EventAttributeRemoved:
+		log.Printf("Attribute %d removed from product %d: %s", event.Attribute.ID, event.ProductID, event.Attribute.Name)
+	case models.EventRatesUpdated:
+		if ratesHandler == nil {
+			log.Printf("No handler for %s message", event.EventType)
+			return nil
+		}
+		if err := ratesHandler(event); err != nil {
+			log.Printf("Failed to apply exchange rates, requeueing: %v", err)
+			return err
+		}
+		log.Printf("Exchange rates updated: %d rates", len(event.Rates))
+	case models.EventSearchDictionaryUpdated, models.EventExchangeRatesChanged, models.EventPromotionsUpdated:
+		// 副本间的内部广播消息，升级前发送的消息可能仍留在队列中，直接忽略
 	default:
 		log.Printf("Unknown event type: %s", event.EventType)
 	}
+	return nil
 }
@@ -0,0 +1,523 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attributeDefinitionColumns 属性定义查询字段，与 scanAttributeDefinition 对应
+const attributeDefinitionColumns = `id, category_id, name, type, aliases, allowed_values, unit, required, created_at, updated_at`
+
+func scanAttributeDefinition(row scanner) (models.AttributeDefinition, error) {
+	var d models.AttributeDefinition
+	var aliases, allowed []byte
+	err := row.Scan(&d.ID, &d.CategoryID, &d.Name, &d.Type, &aliases, &allowed, &d.Unit, &d.Required, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(aliases, &d.Aliases); err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(allowed, &d.AllowedValues); err != nil {
+		return d, err
+	}
+	return d, nil
+}
+
+// loadAttributeDefinitions 加载分类下的全部属性定义
+func loadAttributeDefinitions(q querier, categoryID int) ([]models.AttributeDefinition, error) {
+	rows, err := q.Query(
+		"SELECT "+attributeDefinitionColumns+" FROM attribute_definitions WHERE category_id = ? ORDER BY name",
+		categoryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	definitions := []models.AttributeDefinition{}
+	for rows.Next() {
+		d, err := scanAttributeDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, d)
+	}
+	return definitions, rows.Err()
+}
+
+// matchAttributeDefinition 按属性名或别名查找定义，没有时返回 nil
+func matchAttributeDefinition(definitions []models.AttributeDefinition, name string) *models.AttributeDefinition {
+	for i := range definitions {
+		if definitions[i].Matches(name) {
+			return &definitions[i]
+		}
+	}
+	return nil
+}
+
+// normalizeProductAttribute 按分类的属性定义统一属性名并校验属性值，没有对应定义的属性按自由属性保存
+func normalizeProductAttribute(definitions []models.AttributeDefinition, attr *models.ProductAttribute) (*models.AttributeDefinition, error) {
+	attr.Name = strings.TrimSpace(attr.Name)
+	attr.Value = strings.TrimSpace(attr.Value)
+	d := matchAttributeDefinition(definitions, attr.Name)
+	if d == nil {
+		return nil, nil
+	}
+	value, err := d.NormalizeValue(attr.Value)
+	if err != nil {
+		return d, err
+	}
+	attr.Name, attr.Value = d.Name, value
+	return d, nil
+}
+
+// attributeNumberValue 属性值对应的 number_value 列，不是数值时为 NULL
+func attributeNumberValue(value string) interface{} {
+	if number, ok := models.AttributeNumber(value); ok {
+		return number
+	}
+	return nil
+}
+
+// missingRequiredAttributes 返回商品尚未设置的必填属性名
+func missingRequiredAttributes(q querier, productID, categoryID int) ([]string, error) {
+	definitions, err := loadAttributeDefinitions(q, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	var attributes []models.ProductAttribute
+	if productID > 0 {
+		if attributes, err = loadProductAttributes(q, productID); err != nil {
+			return nil, err
+		}
+	}
+
+	var missing []string
+	for _, d := range definitions {
+		if !d.Required {
+			continue
+		}
+		found := false
+		for _, attr := range attributes {
+			if d.Matches(attr.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, d.Name)
+		}
+	}
+	return missing, nil
+}
+
+// attributeDefinitionConflict 名称或别名与同分类的其他定义重复时返回冲突的定义名
+func attributeDefinitionConflict(definitions []models.AttributeDefinition, d *models.AttributeDefinition) string {
+	for _, other := range definitions {
+		if other.ID == d.ID {
+			continue
+		}
+		for _, name := range append([]string{d.Name}, d.Aliases...) {
+			if other.Matches(name) {
+				return other.Name
+			}
+		}
+	}
+	return ""
+}
+
+// bindAttributeDefinition 解析分类ID和请求体，校验分类存在且名称不与其他定义冲突，已写入响应时返回 false。
+// id 为修改的定义ID，新增时为 0
+func bindAttributeDefinition(c *gin.Context, d *models.AttributeDefinition, id int) bool {
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return false
+	}
+	if err := c.ShouldBindJSON(d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := d.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	d.ID, d.CategoryID = id, categoryID
+
+	var exists bool
+	if err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = ?)", categoryID).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return false
+	}
+
+	definitions, err := loadAttributeDefinitions(database.DB, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if conflict := attributeDefinitionConflict(definitions, d); conflict != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Name or alias conflicts with attribute %s", conflict)})
+		return false
+	}
+	return true
+}
+
+func attributeDefinitionValues(d *models.AttributeDefinition) []interface{} {
+	aliases, _ := json.Marshal(nonNilStrings(d.Aliases))
+	allowed, _ := json.Marshal(nonNilStrings(d.AllowedValues))
+	return []interface{}{d.Name, d.Type, aliases, allowed, d.Unit, d.Required}
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// ListAttributeDefinitions 列出分类的属性定义，GET /categories/:id/attributes
+func ListAttributeDefinitions(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordCategoryOperation("list_attributes", status)
+	}()
+
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	definitions, err := loadAttributeDefinitions(database.DB, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"attributes": definitions})
+}
+
+// CreateAttributeDefinition 为分类新增属性定义
+func CreateAttributeDefinition(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordCategoryOperation("create_attribute", status)
+	}()
+
+	var d models.AttributeDefinition
+	if !bindAttributeDefinition(c, &d, 0) {
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO attribute_definitions (category_id, name, type, aliases, allowed_values, unit, required)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, append([]interface{}{d.CategoryID}, attributeDefinitionValues(&d)...)...)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Attribute already defined for this category"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create attribute definition"})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	middlewares.SetAuditEntity(c, "attribute_definition", id)
+
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// UpdateAttributeDefinition 修改属性定义，已有的商品属性不会自动改写，需要时调用迁移接口
+func UpdateAttributeDefinition(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordCategoryOperation("update_attribute", status)
+	}()
+
+	id, err := strconv.Atoi(c.Param("defId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute definition ID"})
+		return
+	}
+	var d models.AttributeDefinition
+	if !bindAttributeDefinition(c, &d, id) {
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE attribute_definitions
+		SET name = ?, type = ?, aliases = ?, allowed_values = ?, unit = ?, required = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND category_id = ?
+	`, append(attributeDefinitionValues(&d), id, d.CategoryID)...)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Attribute already defined for this category"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update attribute definition"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		var exists bool
+		err := database.DB.QueryRow(
+			"SELECT EXISTS(SELECT 1 FROM attribute_definitions WHERE id = ? AND category_id = ?)", id, d.CategoryID,
+		).Scan(&exists)
+		if err == nil && !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attribute definition not found"})
+			return
+		}
+	}
+
+	middlewares.SetAuditEntity(c, "attribute_definition", id)
+
+	updated, err := scanAttributeDefinition(database.DB.QueryRow(
+		"SELECT "+attributeDefinitionColumns+" FROM attribute_definitions WHERE id = ?", id,
+	))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Attribute definition updated"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteAttributeDefinition 删除属性定义，已有的商品属性保留为自由属性
+func DeleteAttributeDefinition(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordCategoryOperation("delete_attribute", status)
+	}()
+
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("defId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute definition ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM attribute_definitions WHERE id = ? AND category_id = ?", id, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attribute definition"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attribute definition not found"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "attribute_definition", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Attribute definition deleted"})
+}
+
+// attributeMigrationChange 迁移时需要改写的单个属性
+type attributeMigrationChange struct {
+	attributeID int
+	name        string
+	value       string
+	// visible 名称或值发生变化，只补齐数值列时为 false，不发送事件
+	visible bool
+}
+
+// attributeMigrationRow 迁移时扫描到的单个属性
+type attributeMigrationRow struct {
+	productID int
+	attr      models.ProductAttribute
+	number    sql.NullFloat64
+}
+
+// definedAttributeKey 商品上对应同一个属性定义的属性
+type definedAttributeKey struct {
+	productID int
+	name      string
+}
+
+// MigrateCategoryAttributes 按分类的属性定义规范化已有的自由属性：别名和大小写不同的属性名统一为定义的名称，
+// 属性值按类型规范化并补齐数值列。无法规范化的属性值，以及同一商品上对应同一定义的多个属性（如同时有
+// colour 和 Color）保持不变并在结果中列出，需要人工处理；dry_run=true 时只统计不修改
+func MigrateCategoryAttributes(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordCategoryOperation("migrate_attributes", status)
+	}()
+
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	definitions, err := loadAttributeDefinitions(database.DB, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if len(definitions) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category has no attribute definitions"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT pa.id, pa.product_id, pa.name, pa.value, pa.number_value
+		FROM product_attributes pa
+		JOIN products p ON p.id = pa.product_id
+		WHERE p.category_id = ? AND p.deleted_at IS NULL
+		ORDER BY pa.product_id, pa.id
+	`, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	var scanned []attributeMigrationRow
+	for rows.Next() {
+		var row attributeMigrationRow
+		if err := rows.Scan(&row.attr.ID, &row.productID, &row.attr.Name, &row.attr.Value, &row.number); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		scanned = append(scanned, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 统计每个商品对应同一定义的属性数量，改名后会重复的属性不改写
+	defined := map[definedAttributeKey]int{}
+	for _, row := range scanned {
+		if d := matchAttributeDefinition(definitions, strings.TrimSpace(row.attr.Name)); d != nil {
+			defined[definedAttributeKey{row.productID, d.Name}]++
+		}
+	}
+
+	result := models.AttributeMigrationResult{DryRun: dryRun, Invalid: []models.AttributeMigrationIssue{}}
+	changes := map[int][]attributeMigrationChange{}
+	var productIDs []int
+	for _, row := range scanned {
+		productID, attr, number := row.productID, row.attr, row.number
+
+		normalized := attr
+		d, err := normalizeProductAttribute(definitions, &normalized)
+		if d == nil {
+			continue
+		}
+		if defined[definedAttributeKey{productID, d.Name}] > 1 {
+			result.Invalid = append(result.Invalid, models.AttributeMigrationIssue{
+				ProductID: productID, AttributeID: attr.ID, Name: attr.Name, Value: attr.Value,
+				Error: fmt.Sprintf("product has more than one attribute for %q", d.Name),
+			})
+			continue
+		}
+		if err != nil {
+			result.Invalid = append(result.Invalid, models.AttributeMigrationIssue{
+				ProductID: productID, AttributeID: attr.ID, Name: attr.Name, Value: attr.Value, Error: err.Error(),
+			})
+			continue
+		}
+
+		renamed := normalized.Name != attr.Name
+		changedValue := normalized.Value != attr.Value
+		expected, hasNumber := models.AttributeNumber(normalized.Value)
+		staleNumber := hasNumber != number.Valid || (hasNumber && expected != number.Float64)
+		if !renamed && !changedValue && !staleNumber {
+			continue
+		}
+		if renamed {
+			result.Renamed++
+		}
+		if changedValue {
+			result.Normalized++
+		}
+		if _, ok := changes[productID]; !ok {
+			productIDs = append(productIDs, productID)
+		}
+		changes[productID] = append(changes[productID], attributeMigrationChange{
+			attributeID: attr.ID, name: normalized.Name, value: normalized.Value, visible: renamed || changedValue,
+		})
+	}
+
+	if !dryRun {
+		var migrated []int
+		for _, productID := range productIDs {
+			if err := migrateProductAttributes(productID, changes[productID], c.GetInt("userID")); err != nil {
+				log.Printf("Failed to migrate attributes of product %d: %v", productID, err)
+				continue
+			}
+			migrated = append(migrated, productID)
+		}
+		productIDs = migrated
+		reindexProducts(migrated...)
+	}
+	result.Products = len(productIDs)
+
+	middlewares.SetAuditEntity(c, "category", categoryID)
+	c.JSON(http.StatusOK, result)
+}
+
+// migrateProductAttributes 在一个事务中改写单个商品的属性并记录修订，提交后逐个发送属性变更事件
+func migrateProductAttributes(productID int, changes []attributeMigrationChange, actorID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(tx, productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		_, err := tx.Exec(
+			"UPDATE product_attributes SET name = ?, value = ?, number_value = ? WHERE id = ? AND product_id = ?",
+			change.name, change.value, attributeNumberValue(change.value), change.attributeID, productID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := recordRevision(tx, productID, models.RevisionActionNormalizeAttributes, actorID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if rabbitMQ != nil {
+		for _, change := range changes {
+			if !change.visible {
+				continue
+			}
+			sendProductEvent(models.EventAttributeUpdated, productID, models.ProductAttribute{
+				ID: change.attributeID, Name: change.name, Value: change.value,
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/models"
+	"product-service/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const auditLogColumns = `id, occurred_at, user_id, api_key_fingerprint, service_identity, client_ip,
+	       request_id, method, route, path, entity_type, entity_id, status_code, outcome, latency_ms`
+
+func scanAuditLog(row scanner, entry *models.AuditLog) error {
+	return row.Scan(
+		&entry.ID, &entry.OccurredAt, &entry.UserID, &entry.ClaimedAPIKeyFingerprint, &entry.ClaimedServiceIdentity,
+		&entry.ClientIP, &entry.RequestID, &entry.Method, &entry.Route, &entry.Path,
+		&entry.EntityType, &entry.EntityID, &entry.StatusCode, &entry.Outcome, &entry.LatencyMs,
+	)
+}
+
+// parseAuditTime 支持 RFC3339 和 YYYY-MM-DD 两种格式
+func parseAuditTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+func buildAuditWhere(filter models.AuditFilter) (string, []interface{}, error) {
+	where := " WHERE 1 = 1"
+	var args []interface{}
+
+	if filter.UserID > 0 {
+		where += " AND user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.EntityType != "" {
+		where += " AND entity_type = ?"
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		where += " AND entity_id = ?"
+		args = append(args, filter.EntityID)
+	}
+	if filter.Route != "" {
+		where += " AND route = ?"
+		args = append(args, filter.Route)
+	}
+	if filter.Method != "" {
+		where += " AND method = ?"
+		args = append(args, filter.Method)
+	}
+	if filter.Outcome != "" {
+		where += " AND outcome = ?"
+		args = append(args, filter.Outcome)
+	}
+	if filter.RequestID != "" {
+		where += " AND request_id = ?"
+		args = append(args, filter.RequestID)
+	}
+	if filter.From != "" {
+		from, err := parseAuditTime(filter.From)
+		if err != nil {
+			return "", nil, err
+		}
+		where += " AND occurred_at >= ?"
+		args = append(args, from)
+	}
+	if filter.To != "" {
+		to, err := parseAuditTime(filter.To)
+		if err != nil {
+			return "", nil, err
+		}
+		where += " AND occurred_at < ?"
+		args = append(args, to)
+	}
+
+	return where, args, nil
+}
+
+// ListAuditLogs 查询审计日志，format=csv 时导出全部匹配记录
+func ListAuditLogs(c *gin.Context) {
+	var filter models.AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	where, args, err := buildAuditWhere(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range, expected RFC3339 or YYYY-MM-DD"})
+		return
+	}
+
+	if filter.Format == "csv" {
+		exportAuditLogs(c, where, args)
+		return
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM audit_logs"+where, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		return
+	}
+
+	pageArgs := append(args, pagination.PageSize, (pagination.Page-1)*pagination.PageSize)
+	rows, err := database.DB.Query(
+		"SELECT "+auditLogColumns+" FROM audit_logs"+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		pageArgs...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		var entry models.AuditLog
+		if err := scanAuditLog(rows, &entry); err != nil {
+			log.Printf("Error scanning audit log: %v", err)
+			continue
+		}
+		logs = append(logs, entry)
+	}
+
+	c.JSON(http.StatusOK, models.AuditLogResponse{
+		Logs:      logs,
+		Total:     total,
+		Page:      pagination.Page,
+		PageSize:  pagination.PageSize,
+		TotalPage: utils.CalculateTotalPages(total, pagination.PageSize),
+	})
+}
+
+// exportAuditLogs 以CSV流式导出审计日志
+func exportAuditLogs(c *gin.Context, where string, args []interface{}) {
+	rows, err := database.DB.Query(
+		"SELECT "+auditLogColumns+" FROM audit_logs"+where+" ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="audit_logs.csv"`)
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.Write([]string{
+		"id", "occurred_at", "user_id", "claimed_api_key_fingerprint", "claimed_service_identity", "client_ip",
+		"request_id", "method", "route", "path", "entity_type", "entity_id", "status_code",
+		"outcome", "latency_ms",
+	})
+
+	count := 0
+	for rows.Next() {
+		var entry models.AuditLog
+		if err := scanAuditLog(rows, &entry); err != nil {
+			log.Printf("Error scanning audit log: %v", err)
+			continue
+		}
+		_ = writer.Write([]string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.OccurredAt.Format(time.RFC3339Nano),
+			strconv.Itoa(entry.UserID),
+			entry.ClaimedAPIKeyFingerprint,
+			entry.ClaimedServiceIdentity,
+			entry.ClientIP,
+			entry.RequestID,
+			entry.Method,
+			entry.Route,
+			entry.Path,
+			entry.EntityType,
+			entry.EntityID,
+			strconv.Itoa(entry.StatusCode),
+			entry.Outcome,
+			strconv.FormatInt(entry.LatencyMs, 10),
+		})
+
+		count++
+		if count%500 == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("Failed to export audit logs: %v", err)
+	}
+}
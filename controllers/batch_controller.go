@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"product-service/config"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batchGetMaxIDs 批量获取单次最多的ID数量
+var batchGetMaxIDs = 300
+
+// InitBatchGet 加载批量获取配置
+func InitBatchGet(cfg *config.Config) {
+	if cfg.BatchGetMaxIDs > 0 {
+		batchGetMaxIDs = cfg.BatchGetMaxIDs
+	}
+}
+
+// parseProductIDList 解析逗号分隔的商品ID列表
+func parseProductIDList(value string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid product ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetProductsBatch 按ID批量获取商品，POST /products/batch
+func GetProductsBatch(c *gin.Context) {
+	var req models.ProductBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middlewares.RecordProductOperation("batch_get", false)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	respondProductsBatch(c, req.IDs)
+}
+
+// respondProductsBatch 加载商品及其属性、图片，查询次数与ID数量无关
+func respondProductsBatch(c *gin.Context, ids []int) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("batch_get", status)
+	}()
+
+	// 去重并保持请求顺序
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid product ID %d", id)})
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must not be empty"})
+		return
+	}
+	if len(unique) > batchGetMaxIDs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d ids per request", batchGetMaxIDs)})
+		return
+	}
+
+	// 批量获取总是包含属性和图片，fields 只影响输出
+	var includes productIncludes
+	fields, err := parseProductFields(c.Query("fields"), &includes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	products, err := loadProductDetailsByID(database.DB, unique)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if err := applyPromotions(database.DB, products, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 未发布的商品只对已认证的调用方可见，其余调用方视为不存在
+	viewUnpublished := canViewUnpublished(c)
+	byID := make(map[int]models.ProductDetail, len(products))
+	for _, p := range products {
+		if p.Status == models.ProductStatusPublished || viewUnpublished {
+			byID[p.ID] = p
+		}
+	}
+
+	response := models.ProductBatchResponse{
+		Products: make([]models.ProductDetail, 0, len(byID)),
+		Missing:  []int{},
+	}
+	for _, id := range unique {
+		if p, ok := byID[id]; ok {
+			response.Products = append(response.Products, p)
+		} else {
+			response.Missing = append(response.Missing, id)
+		}
+	}
+
+	renderProducts(c, response, response.Products, fields)
+}
@@ -0,0 +1,529 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"product-service/config"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 换算系数保留的小数位数，SQL 过滤和 Go 计算使用相同的系数，保证结果一致
+const conversionFactorScale = 18
+
+// 汇率在数据库中保存的小数位数，与 exchange_rates.rate 列一致
+const exchangeRateScale = 15
+
+// exchangeRate 以默认币种为基准的汇率
+type exchangeRate struct {
+	rate      *big.Rat
+	updatedAt time.Time
+}
+
+// exchangeRateTable 进程内缓存的汇率，各副本从数据库加载
+type exchangeRateTable struct {
+	mu    sync.RWMutex
+	rates map[string]exchangeRate
+}
+
+var exchangeRates = &exchangeRateTable{rates: map[string]exchangeRate{}}
+
+// currencyRounding 汇率换算的舍入规则
+var currencyRounding = struct {
+	mode      string
+	overrides map[string]string
+}{mode: models.RoundingHalfUp, overrides: map[string]string{}}
+
+// InitExchangeRates 加载舍入规则配置
+func InitExchangeRates(cfg *config.Config) {
+	mode, overrides := parseCurrencyRounding(cfg.CurrencyRounding)
+	currencyRounding.mode = mode
+	currencyRounding.overrides = overrides
+}
+
+// parseCurrencyRounding 解析 "half_up,JPY=up" 形式的舍入规则，无效的项忽略
+func parseCurrencyRounding(value string) (string, map[string]string) {
+	mode := models.RoundingHalfUp
+	overrides := map[string]string{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		currency, rule, qualified := strings.Cut(part, "=")
+		if !qualified {
+			rule = currency
+		}
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if !validRoundingMode(rule) {
+			log.Printf("Ignoring invalid CURRENCY_ROUNDING entry %q", part)
+			continue
+		}
+		if !qualified {
+			mode = rule
+			continue
+		}
+		code, err := models.NormalizeCurrency(currency)
+		if err != nil {
+			log.Printf("Ignoring invalid CURRENCY_ROUNDING entry %q", part)
+			continue
+		}
+		overrides[code] = rule
+	}
+	return mode, overrides
+}
+
+func validRoundingMode(mode string) bool {
+	switch mode {
+	case models.RoundingHalfUp, models.RoundingDown, models.RoundingUp:
+		return true
+	}
+	return false
+}
+
+// roundingFor 换算到指定币种时使用的舍入规则
+func roundingFor(currency string) string {
+	if mode, ok := currencyRounding.overrides[currency]; ok {
+		return mode
+	}
+	return currencyRounding.mode
+}
+
+// load 替换全部汇率
+func (t *exchangeRateTable) load(rates map[string]exchangeRate) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rates = rates
+}
+
+// lookup 以默认币种为基准的汇率，默认币种自身的汇率为 1
+func (t *exchangeRateTable) lookup(currency string) (exchangeRate, bool) {
+	if currency == models.DefaultCurrency {
+		return exchangeRate{rate: big.NewRat(1, 1)}, true
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rate, ok := t.rates[currency]
+	return rate, ok
+}
+
+// currencies 有汇率的全部币种（包括默认币种），按字母排序
+func (t *exchangeRateTable) currencies() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	codes := []string{models.DefaultCurrency}
+	for code := range t.rates {
+		if code != models.DefaultCurrency {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// applied 从 from 换算到 to 使用的汇率，没有汇率时返回 false
+func (t *exchangeRateTable) applied(from, to string) (*models.AppliedExchangeRate, bool) {
+	fromRate, ok := t.lookup(from)
+	if !ok {
+		return nil, false
+	}
+	toRate, ok := t.lookup(to)
+	if !ok {
+		return nil, false
+	}
+
+	cross := new(big.Rat).Quo(toRate.rate, fromRate.rate)
+	applied := &models.AppliedExchangeRate{
+		From: from,
+		To:   to,
+		Rate: trimDecimal(cross.FloatString(exchangeRateScale)),
+	}
+	// 交叉汇率的时效以较早更新的一方为准，默认币种没有更新时间
+	for _, rate := range []exchangeRate{fromRate, toRate} {
+		if !rate.updatedAt.IsZero() && (applied.UpdatedAt.IsZero() || rate.updatedAt.Before(applied.UpdatedAt)) {
+			applied.UpdatedAt = rate.updatedAt
+		}
+	}
+	return applied, true
+}
+
+// factor 将 from 币种的最小单位金额换算为 to 币种最小单位的系数，截断到 conversionFactorScale 位小数
+func (t *exchangeRateTable) factor(from, to string) (*big.Rat, bool) {
+	fromRate, ok := t.lookup(from)
+	if !ok {
+		return nil, false
+	}
+	toRate, ok := t.lookup(to)
+	if !ok {
+		return nil, false
+	}
+	fromExp, _ := models.CurrencyExponent(from)
+	toExp, _ := models.CurrencyExponent(to)
+
+	f := new(big.Rat).Quo(toRate.rate, fromRate.rate)
+	f.Mul(f, pow10Rat(toExp-fromExp))
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(conversionFactorScale), nil)
+	scaled := new(big.Int).Mul(f.Num(), scale)
+	scaled.Quo(scaled, f.Denom())
+	return new(big.Rat).SetFrac(scaled, scale), true
+}
+
+// convert 将金额按汇率换算为 to 币种，没有汇率或换算结果超出 int64 时返回 false
+func (t *exchangeRateTable) convert(m models.Money, to string) (models.Money, bool) {
+	f, ok := t.factor(m.Currency, to)
+	if !ok {
+		return models.Money{}, false
+	}
+	amount, ok := roundRat(new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), f), roundingFor(to))
+	if !ok {
+		return models.Money{}, false
+	}
+	return models.Money{Amount: amount, Currency: to}, true
+}
+
+// conversionExpr 将商品基础价格换算为 currency 币种最小单位的 SQL 表达式，
+// 舍入方式与 convert 一致，基础币种没有汇率时为 -1
+func (t *exchangeRateTable) conversionExpr(currency string) string {
+	round := "ROUND"
+	switch roundingFor(currency) {
+	case models.RoundingDown:
+		round = "FLOOR"
+	case models.RoundingUp:
+		round = "CEILING"
+	}
+
+	var whens []string
+	for _, from := range t.currencies() {
+		if from == currency {
+			continue
+		}
+		f, ok := t.factor(from, currency)
+		if !ok {
+			continue
+		}
+		whens = append(whens, fmt.Sprintf(" WHEN '%s' THEN CAST(%s(p.price_amount * %s) AS SIGNED)",
+			from, round, f.FloatString(conversionFactorScale)))
+	}
+	if len(whens) == 0 {
+		return "-1"
+	}
+	return "(CASE p.currency" + strings.Join(whens, "") + " ELSE -1 END)"
+}
+
+// roundRat 按舍入规则取整，half_up 与 MySQL ROUND 一致为远离零舍入，结果超出 int64 时返回 false
+func roundRat(r *big.Rat, mode string) (int64, bool) {
+	num, den := r.Num(), r.Denom()
+	// big.Int.Div 为欧几里得除法，分母为正时即向下取整
+	rounded := new(big.Int).Div(num, den)
+	exact := new(big.Int).Mul(rounded, den).Cmp(num) == 0
+
+	switch mode {
+	case models.RoundingDown:
+	case models.RoundingUp:
+		if !exact {
+			rounded.Add(rounded, big.NewInt(1))
+		}
+	default:
+		abs := new(big.Rat).Abs(r)
+		abs.Add(abs, big.NewRat(1, 2))
+		rounded.Div(abs.Num(), abs.Denom())
+		if r.Sign() < 0 {
+			rounded.Neg(rounded)
+		}
+	}
+
+	if !rounded.IsInt64() {
+		return 0, false
+	}
+	return rounded.Int64(), true
+}
+
+func pow10Rat(exp int) *big.Rat {
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(absInt(exp))), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// trimDecimal 去掉十进制字符串末尾多余的零
+func trimDecimal(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// applyDisplayPrice 设置商品在指定币种下的展示价格：优先使用基础价格或价格表，
+// 否则按汇率换算并附带所用汇率，都没有时不设置
+func applyDisplayPrice(p *models.ProductDetail, currency string) {
+	if p.Price.Currency == currency {
+		price := p.Price
+		p.DisplayPrice = &price
+		return
+	}
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			price := price
+			p.DisplayPrice = &price
+			return
+		}
+	}
+
+	converted, ok := exchangeRates.convert(p.Price, currency)
+	if !ok {
+		return
+	}
+	p.DisplayPrice = &converted
+	p.ExchangeRate, _ = exchangeRates.applied(p.Price.Currency, currency)
+}
+
+// loadExchangeRates 从数据库重新加载以默认币种为基准的汇率
+func loadExchangeRates() error {
+	rows, err := database.DB.Query(
+		"SELECT currency, rate, updated_at FROM exchange_rates WHERE base = ?",
+		models.DefaultCurrency,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rates := map[string]exchangeRate{}
+	for rows.Next() {
+		var currency, value string
+		var rate exchangeRate
+		if err := rows.Scan(&currency, &value, &rate.updatedAt); err != nil {
+			return err
+		}
+		r, ok := new(big.Rat).SetString(value)
+		if !ok || r.Sign() <= 0 {
+			log.Printf("Ignoring invalid exchange rate %s=%s", currency, value)
+			continue
+		}
+		rate.rate = r
+		rates[currency] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	exchangeRates.load(rates)
+	return nil
+}
+
+// StartExchangeRateRefresher 启动时加载汇率并定期重新加载，作为变更事件丢失时的兜底
+func StartExchangeRateRefresher(interval time.Duration) {
+	if err := loadExchangeRates(); err != nil {
+		log.Printf("Failed to load exchange rates: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := loadExchangeRates(); err != nil {
+			log.Printf("Failed to reload exchange rates: %v", err)
+		}
+	}
+}
+
+// HandleExchangeRateEvent 汇率写入数据库后各副本重新加载
+func HandleExchangeRateEvent(event models.ProductEvent) {
+	if event.EventType != models.EventExchangeRatesChanged {
+		return
+	}
+	if err := loadExchangeRates(); err != nil {
+		log.Printf("Failed to reload exchange rates: %v", err)
+	}
+}
+
+// exchangeRatesChanged 本副本立即重新加载，并通知其他副本
+func exchangeRatesChanged() {
+	if err := loadExchangeRates(); err != nil {
+		log.Printf("Failed to reload exchange rates: %v", err)
+	}
+	sendBroadcastEvent(models.EventExchangeRatesChanged)
+}
+
+// normalizeExchangeRates 校验汇率并换算为以默认币种为基准，未指定更新时间的汇率使用 at
+func normalizeExchangeRates(update models.ExchangeRatesUpdate, at time.Time) (map[string]exchangeRate, error) {
+	base := models.DefaultCurrency
+	if update.Base != "" {
+		code, err := models.NormalizeCurrency(update.Base)
+		if err != nil {
+			return nil, err
+		}
+		base = code
+	}
+
+	rates := map[string]exchangeRate{}
+	for _, r := range update.Rates {
+		code, err := models.NormalizeCurrency(r.Currency)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := new(big.Rat).SetString(r.Rate.String())
+		if !ok || value.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", r.Rate, code)
+		}
+		if _, dup := rates[code]; dup {
+			return nil, fmt.Errorf("duplicate rate for %s", code)
+		}
+		updatedAt := r.UpdatedAt
+		if updatedAt.IsZero() {
+			updatedAt = at
+		}
+		rates[code] = exchangeRate{rate: value, updatedAt: updatedAt}
+	}
+
+	// 以其他币种为基准时，通过默认币种的汇率换算
+	if base != models.DefaultCurrency {
+		pivot, ok := rates[models.DefaultCurrency]
+		if !ok {
+			return nil, fmt.Errorf("rates based on %s must include %s", base, models.DefaultCurrency)
+		}
+		converted := map[string]exchangeRate{
+			base: {rate: new(big.Rat).Inv(pivot.rate), updatedAt: pivot.updatedAt},
+		}
+		for code, r := range rates {
+			if code == base {
+				continue
+			}
+			converted[code] = exchangeRate{rate: new(big.Rat).Quo(r.rate, pivot.rate), updatedAt: r.updatedAt}
+		}
+		rates = converted
+	}
+	delete(rates, models.DefaultCurrency)
+
+	if len(rates) == 0 {
+		return nil, errors.New("rates must include at least one currency other than " + models.DefaultCurrency)
+	}
+	return rates, nil
+}
+
+// storeExchangeRates 写入汇率，已有的币种只被更新时间不早于现有记录的汇率覆盖，
+// 避免延迟到达的旧消息覆盖较新的汇率
+func storeExchangeRates(rates map[string]exchangeRate) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for code, r := range rates {
+		_, err := tx.Exec(`
+			INSERT INTO exchange_rates (base, currency, rate, updated_at)
+			VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				rate = IF(VALUES(updated_at) >= updated_at, VALUES(rate), rate),
+				updated_at = GREATEST(updated_at, VALUES(updated_at))
+		`, models.DefaultCurrency, code, r.rate.FloatString(exchangeRateScale), r.updatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ApplyRatesUpdate 处理 rates_updated 消息：保存汇率并通知各副本重新加载。
+// 消息内容无效时记录日志并丢弃，只有保存失败时返回错误，由消费者重新入队
+func ApplyRatesUpdate(event models.ProductEvent) error {
+	at := event.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	rates, err := normalizeExchangeRates(models.ExchangeRatesUpdate{Base: event.RatesBase, Rates: event.Rates}, at)
+	if err != nil {
+		log.Printf("Discarding invalid %s message %s: %v", event.EventType, event.EventID, err)
+		return nil
+	}
+	if err := storeExchangeRates(rates); err != nil {
+		return err
+	}
+	exchangeRatesChanged()
+	return nil
+}
+
+// ListExchangeRates 列出以默认币种为基准的全部汇率
+func ListExchangeRates(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("list_exchange_rates", status)
+	}()
+
+	rows, err := database.DB.Query(
+		"SELECT currency, rate, updated_at FROM exchange_rates WHERE base = ? ORDER BY currency",
+		models.DefaultCurrency,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		var r models.ExchangeRate
+		var value string
+		if err := rows.Scan(&r.Currency, &value, &r.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		r.Rate = json.Number(trimDecimal(value))
+		rates = append(rates, r)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"base": models.DefaultCurrency, "rates": rates})
+}
+
+// UpdateExchangeRates 批量设置汇率
+func UpdateExchangeRates(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("update_exchange_rates", status)
+	}()
+
+	var update models.ExchangeRatesUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rates, err := normalizeExchangeRates(update, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := storeExchangeRates(rates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exchange rates"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "exchange_rates", models.DefaultCurrency)
+	exchangeRatesChanged()
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rates updated", "updated": len(rates)})
+}
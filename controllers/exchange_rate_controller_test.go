@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"math"
+	"math/big"
+	"product-service/models"
+	"testing"
+)
+
+func TestRoundRat(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  *big.Rat
+		mode   string
+		want   int64
+		wantOK bool
+	}{
+		{"half up rounds half away from zero", big.NewRat(5, 2), models.RoundingHalfUp, 3, true},
+		{"half up negative half", big.NewRat(-5, 2), models.RoundingHalfUp, -3, true},
+		{"half up below half", big.NewRat(249, 100), models.RoundingHalfUp, 2, true},
+		{"half up negative below half", big.NewRat(-249, 100), models.RoundingHalfUp, -2, true},
+		{"half up exact", big.NewRat(7, 1), models.RoundingHalfUp, 7, true},
+		{"down truncates toward negative infinity", big.NewRat(29, 10), models.RoundingDown, 2, true},
+		{"down negative", big.NewRat(-21, 10), models.RoundingDown, -3, true},
+		{"down exact", big.NewRat(-4, 1), models.RoundingDown, -4, true},
+		{"up rounds toward positive infinity", big.NewRat(21, 10), models.RoundingUp, 3, true},
+		{"up negative", big.NewRat(-29, 10), models.RoundingUp, -2, true},
+		{"up exact", big.NewRat(4, 1), models.RoundingUp, 4, true},
+		{"zero", new(big.Rat), models.RoundingUp, 0, true},
+		{"unknown mode falls back to half up", big.NewRat(3, 2), "", 2, true},
+		{"max int64", new(big.Rat).SetInt64(math.MaxInt64), models.RoundingHalfUp, math.MaxInt64, true},
+		{"min int64", new(big.Rat).SetInt64(math.MinInt64), models.RoundingDown, math.MinInt64, true},
+		{"up overflows int64", new(big.Rat).Add(new(big.Rat).SetInt64(math.MaxInt64), big.NewRat(1, 2)), models.RoundingUp, 0, false},
+		{"half up overflows int64", new(big.Rat).Add(new(big.Rat).SetInt64(math.MaxInt64), big.NewRat(1, 2)), models.RoundingHalfUp, 0, false},
+		{"down overflows int64", new(big.Rat).Sub(new(big.Rat).SetInt64(math.MinInt64), big.NewRat(1, 2)), models.RoundingDown, 0, false},
+		{"far beyond int64", new(big.Rat).SetFrac(new(big.Int).Lsh(big.NewInt(1), 80), big.NewInt(3)), models.RoundingHalfUp, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := roundRat(tt.value, tt.mode)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("roundRat(%s, %q) = %d, %v, want %d, %v", tt.value.RatString(), tt.mode, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// testExchangeRates 以默认币种为基准：1 基础币种 = 0.14 USD = 20 JPY
+func testExchangeRates() *exchangeRateTable {
+	return &exchangeRateTable{rates: map[string]exchangeRate{
+		"USD": {rate: big.NewRat(14, 100)},
+		"JPY": {rate: big.NewRat(20, 1)},
+	}}
+}
+
+func TestExchangeRateFactor(t *testing.T) {
+	defer func(currency string) { models.DefaultCurrency = currency }(models.DefaultCurrency)
+	models.DefaultCurrency = "CNY"
+	table := testExchangeRates()
+
+	tests := []struct {
+		name   string
+		from   string
+		to     string
+		want   string
+		wantOK bool
+	}{
+		{"same currency", "CNY", "CNY", "1.000000000000000000", true},
+		{"default to currency with same exponent", "CNY", "USD", "0.140000000000000000", true},
+		{"default to zero exponent currency", "CNY", "JPY", "0.200000000000000000", true},
+		{"zero exponent to two decimal currency", "JPY", "USD", "0.700000000000000000", true},
+		{"two decimal to zero exponent currency", "USD", "JPY", "1.428571428571428571", true},
+		{"back to default currency", "JPY", "CNY", "5.000000000000000000", true},
+		{"unknown source currency", "EUR", "CNY", "", false},
+		{"unknown target currency", "CNY", "EUR", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := table.factor(tt.from, tt.to)
+			if ok != tt.wantOK {
+				t.Fatalf("factor(%q, %q) ok = %v, want %v", tt.from, tt.to, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got := f.FloatString(conversionFactorScale); got != tt.want {
+				t.Errorf("factor(%q, %q) = %s, want %s", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeRateConvert(t *testing.T) {
+	defer func(currency string) { models.DefaultCurrency = currency }(models.DefaultCurrency)
+	models.DefaultCurrency = "CNY"
+	table := testExchangeRates()
+
+	tests := []struct {
+		name   string
+		money  models.Money
+		to     string
+		want   models.Money
+		wantOK bool
+	}{
+		{"default to USD", models.Money{Amount: 1000, Currency: "CNY"}, "USD", models.Money{Amount: 140, Currency: "USD"}, true},
+		{"default to JPY", models.Money{Amount: 1999, Currency: "CNY"}, "JPY", models.Money{Amount: 400, Currency: "JPY"}, true},
+		{"JPY to USD", models.Money{Amount: 1500, Currency: "JPY"}, "USD", models.Money{Amount: 1050, Currency: "USD"}, true},
+		{"USD to JPY", models.Money{Amount: 100, Currency: "USD"}, "JPY", models.Money{Amount: 143, Currency: "JPY"}, true},
+		{"missing rate", models.Money{Amount: 100, Currency: "CNY"}, "EUR", models.Money{}, false},
+		{"overflow", models.Money{Amount: math.MaxInt64, Currency: "USD"}, "JPY", models.Money{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := table.convert(tt.money, tt.to)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("convert(%+v, %q) = %+v, %v, want %+v, %v", tt.money, tt.to, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
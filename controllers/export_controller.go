@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"product-service/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 每次从数据库读取的商品数量
+const exportChunkSize = 500
+
+// exportColumn 导出列定义
+type exportColumn struct {
+	Name  string
+	Value func(p *models.ProductDetail) interface{}
+}
+
+// exportColumns 可导出的列，默认按此顺序全部导出
+var exportColumns = []exportColumn{
+	{"id", func(p *models.ProductDetail) interface{} { return p.ID }},
+	{"name", func(p *models.ProductDetail) interface{} { return p.Name }},
+	{"description", func(p *models.ProductDetail) interface{} { return p.Description }},
+	{"price", func(p *models.ProductDetail) interface{} { return p.Price.Decimal() }},
+	{"currency", func(p *models.ProductDetail) interface{} { return p.Price.Currency }},
+	{"stock", func(p *models.ProductDetail) interface{} { return p.Stock }},
+	{"category_id", func(p *models.ProductDetail) interface{} { return p.CategoryID }},
+	{"category_name", func(p *models.ProductDetail) interface{} { return p.CategoryName }},
+	{"sku", func(p *models.ProductDetail) interface{} { return p.SKU }},
+	{"gtin", func(p *models.ProductDetail) interface{} { return p.GTIN }},
+	{"weight", func(p *models.ProductDetail) interface{} { return p.Weight.String() }},
+	{"dimensions", func(p *models.ProductDetail) interface{} { return p.Dimensions.String() }},
+	{"image_url", func(p *models.ProductDetail) interface{} { return p.ImageURL }},
+	{"status", func(p *models.ProductDetail) interface{} { return p.Status }},
+	{"created_at", func(p *models.ProductDetail) interface{} { return p.CreatedAt }},
+	{"updated_at", func(p *models.ProductDetail) interface{} { return p.UpdatedAt }},
+	{"attributes", func(p *models.ProductDetail) interface{} { return p.Attributes }},
+	{"images", func(p *models.ProductDetail) interface{} { return p.Images }},
+	{"prices", func(p *models.ProductDetail) interface{} { return p.Prices }},
+}
+
+// selectExportColumns 解析 columns 参数，未指定时返回全部列
+func selectExportColumns(param string) ([]exportColumn, error) {
+	if strings.TrimSpace(param) == "" {
+		return exportColumns, nil
+	}
+
+	byName := make(map[string]exportColumn, len(exportColumns))
+	for _, col := range exportColumns {
+		byName[col.Name] = col
+	}
+
+	var selected []exportColumn
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		col, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		selected = append(selected, col)
+	}
+	return selected, nil
+}
+
+// formatExportCell 将列值格式化为表格单元格，属性写为 name=value; 形式，图片地址和价格表以 ; 分隔
+func formatExportCell(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []models.ProductAttribute:
+		parts := make([]string, len(v))
+		for i, attr := range v {
+			parts[i] = attr.Name + "=" + attr.Value
+		}
+		return strings.Join(parts, "; ")
+	case []models.ProductImage:
+		parts := make([]string, len(v))
+		for i, img := range v {
+			parts[i] = img.ImageURL
+		}
+		return strings.Join(parts, "; ")
+	case []models.Money:
+		parts := make([]string, len(v))
+		for i, price := range v {
+			parts[i] = price.String()
+		}
+		return strings.Join(parts, "; ")
+	}
+	return value
+}
+
+// productExportWriter 导出格式写入器
+type productExportWriter interface {
+	WriteHeader(columns []exportColumn) error
+	WriteProduct(columns []exportColumn, p *models.ProductDetail) error
+	Flush() error
+	Close() error
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvExportWriter) WriteHeader(columns []exportColumn) error {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	return w.writer.Write(names)
+}
+
+func (w *csvExportWriter) WriteProduct(columns []exportColumn, p *models.ProductDetail) error {
+	record := make([]string, len(columns))
+	for i, col := range columns {
+		switch v := formatExportCell(col.Value(p)).(type) {
+		case string:
+			record[i] = v
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvExportWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvExportWriter) Close() error {
+	return w.Flush()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonExportWriter) WriteHeader(columns []exportColumn) error {
+	return nil
+}
+
+func (w *ndjsonExportWriter) WriteProduct(columns []exportColumn, p *models.ProductDetail) error {
+	record := make(map[string]interface{}, len(columns))
+	for _, col := range columns {
+		record[col.Name] = col.Value(p)
+	}
+	return w.encoder.Encode(record)
+}
+
+func (w *ndjsonExportWriter) Flush() error {
+	return nil
+}
+
+func (w *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+type xlsxExportWriter struct {
+	writer *utils.XLSXWriter
+}
+
+func (w *xlsxExportWriter) WriteHeader(columns []exportColumn) error {
+	names := make([]interface{}, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	return w.writer.WriteRow(names)
+}
+
+func (w *xlsxExportWriter) WriteProduct(columns []exportColumn, p *models.ProductDetail) error {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		values[i] = formatExportCell(col.Value(p))
+	}
+	return w.writer.WriteRow(values)
+}
+
+func (w *xlsxExportWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+func (w *xlsxExportWriter) Close() error {
+	return w.writer.Close()
+}
+
+// ExportProducts 按 ListProducts 相同的过滤条件流式导出全部商品
+func ExportProducts(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("export", status)
+	}()
+	filter, err := bindProductFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	columns, err := selectExportColumns(c.Query("columns"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+	case "ndjson":
+		contentType = "application/x-ndjson"
+	case "xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, ndjson or xlsx"})
+		return
+	}
+
+	query, err := buildProductFilter(filter)
+	if err != nil {
+		log.Printf("Product search failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products-%s.%s"`,
+		time.Now().Format("20060102-150405"), format))
+	c.Status(http.StatusOK)
+
+	var writer productExportWriter
+	switch format {
+	case "csv":
+		writer = &csvExportWriter{writer: csv.NewWriter(c.Writer)}
+	case "ndjson":
+		writer = &ndjsonExportWriter{encoder: json.NewEncoder(c.Writer)}
+	case "xlsx":
+		xw, err := utils.NewXLSXWriter(c.Writer, "Products")
+		if err != nil {
+			log.Printf("Failed to start XLSX export: %v", err)
+			return
+		}
+		writer = &xlsxExportWriter{writer: xw}
+	}
+
+	if err := writer.WriteHeader(columns); err != nil {
+		log.Printf("Failed to write export header: %v", err)
+		return
+	}
+
+	if err := streamProducts(query.where, query.args, func(p *models.ProductDetail) error {
+		return writer.WriteProduct(columns, p)
+	}, func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}); err != nil {
+		// 响应头已发送，只能中断输出并记录日志
+		log.Printf("Product export aborted: %v", err)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("Failed to finish product export: %v", err)
+	}
+}
+
+// streamProducts 按ID分块遍历符合条件的商品（含属性、图片和价格表），每处理完一块调用一次 flush
+func streamProducts(where string, args []interface{}, fn func(p *models.ProductDetail) error, flush func() error) error {
+	lastID := 0
+	for {
+		chunkArgs := append(append([]interface{}{}, args...), lastID, exportChunkSize)
+		rows, err := database.DB.Query(
+			"SELECT "+productDetailColumns+productListFrom+where+" AND p.id > ? ORDER BY p.id LIMIT ?",
+			chunkArgs...,
+		)
+		if err != nil {
+			return err
+		}
+
+		var chunk []models.ProductDetail
+		for rows.Next() {
+			var p models.ProductDetail
+			if err := scanProductDetail(rows, &p); err != nil {
+				rows.Close()
+				return err
+			}
+			chunk = append(chunk, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		ids := make([]int, len(chunk))
+		for i := range chunk {
+			ids[i] = chunk[i].ID
+		}
+		attributes, err := loadAttributesByProduct(database.DB, ids)
+		if err != nil {
+			return err
+		}
+		images, err := loadImagesByProduct(database.DB, ids)
+		if err != nil {
+			return err
+		}
+		prices, err := loadPricesByProduct(database.DB, ids)
+		if err != nil {
+			return err
+		}
+
+		for i := range chunk {
+			chunk[i].Attributes = attributes[chunk[i].ID]
+			chunk[i].Images = images[chunk[i].ID]
+			chunk[i].Prices = prices[chunk[i].ID]
+			if err := fn(&chunk[i]); err != nil {
+				return err
+			}
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+
+		lastID = chunk[len(chunk)-1].ID
+		if len(chunk) < exportChunkSize {
+			return nil
+		}
+	}
+}
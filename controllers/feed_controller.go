@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"product-service/config"
+	"product-service/database"
+	"product-service/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Google Merchant Center 单个商品最多支持10张附加图片
+const maxFeedAdditionalImages = 10
+
+// feedBaseColumns Feed 固定输出的字段，附加字段由 FEED_FIELD_MAP 配置
+var feedBaseColumns = []string{
+	"id", "title", "description", "link", "image_link", "additional_image_link",
+	"availability", "price", "product_type", "condition",
+}
+
+var feedFieldNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// feedFieldMapping 附加字段映射，Source 取值：attr:<属性名>、sku、gtin、category、const:<固定值>
+type feedFieldMapping struct {
+	Field  string
+	Source string
+}
+
+// parseFeedFieldMap 解析形如 brand=attr:brand,mpn=sku 的映射配置
+func parseFeedFieldMap(value string) []feedFieldMapping {
+	var mappings []feedFieldMapping
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || !feedFieldNamePattern.MatchString(parts[0]) {
+			log.Printf("Ignoring invalid feed field mapping %q", pair)
+			continue
+		}
+		mappings = append(mappings, feedFieldMapping{Field: parts[0], Source: strings.TrimSpace(parts[1])})
+	}
+	return mappings
+}
+
+// feedItem 单个商品的 Feed 条目，生成时预先渲染好 XML 片段和 TSV 行
+type feedItem struct {
+	ID  int
+	xml []byte
+	tsv []byte
+}
+
+// productFeed 进程内缓存的商品 Feed，根据商品事件增量刷新
+type productFeed struct {
+	mu          sync.Mutex
+	cfg         *config.Config
+	mappings    []feedFieldMapping
+	items       map[int]*feedItem
+	loaded      bool
+	xmlDoc      []byte
+	tsvDoc      []byte
+	generatedAt time.Time
+}
+
+var feed = &productFeed{}
+
+// InitProductFeed 加载 Feed 配置，需在提供 Feed 接口之前调用
+func InitProductFeed(cfg *config.Config) {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	feed.cfg = cfg
+	feed.mappings = parseFeedFieldMap(cfg.FeedFieldMap)
+}
+
+func (f *productFeed) columns() []string {
+	columns := append([]string{}, feedBaseColumns...)
+	for _, m := range f.mappings {
+		columns = append(columns, m.Field)
+	}
+	return columns
+}
+
+// buildItem 将商品转换为 Feed 条目
+func (f *productFeed) buildItem(p *models.ProductDetail) *feedItem {
+	values := map[string]string{
+		"id":           strconv.Itoa(p.ID),
+		"title":        p.Name,
+		"description":  p.Description,
+		"link":         strings.ReplaceAll(f.cfg.FeedProductURL, "{id}", strconv.Itoa(p.ID)),
+		"availability": "out_of_stock",
+		"price":        f.price(p).String(),
+		"product_type": p.CategoryName,
+		"condition":    "new",
+	}
+	if p.Stock > 0 {
+		values["availability"] = "in_stock"
+	}
+
+	// 主图优先取 product_images 中标记为主图的图片，其次是第一张图片，最后是商品自身的 image_url
+	var additional []string
+	for _, img := range p.Images {
+		if img.IsPrimary && values["image_link"] == "" {
+			values["image_link"] = f.imageURL(img.ImageURL)
+			continue
+		}
+		additional = append(additional, f.imageURL(img.ImageURL))
+	}
+	if values["image_link"] == "" && len(additional) > 0 {
+		values["image_link"] = additional[0]
+		additional = additional[1:]
+	}
+	if values["image_link"] == "" && p.ImageURL != "" {
+		values["image_link"] = f.imageURL(p.ImageURL)
+	}
+	if len(additional) > maxFeedAdditionalImages {
+		additional = additional[:maxFeedAdditionalImages]
+	}
+
+	for _, m := range f.mappings {
+		values[m.Field] = f.mappedValue(p, m.Source)
+	}
+
+	item := &feedItem{ID: p.ID}
+
+	var xmlBuf bytes.Buffer
+	xmlBuf.WriteString("<item>")
+	for _, column := range f.columns() {
+		if column == "additional_image_link" {
+			for _, link := range additional {
+				writeFeedXMLElement(&xmlBuf, column, link)
+			}
+			continue
+		}
+		if values[column] != "" {
+			writeFeedXMLElement(&xmlBuf, column, values[column])
+		}
+	}
+	xmlBuf.WriteString("</item>\n")
+	item.xml = xmlBuf.Bytes()
+
+	var tsvBuf bytes.Buffer
+	for i, column := range f.columns() {
+		if i > 0 {
+			tsvBuf.WriteByte('\t')
+		}
+		value := values[column]
+		if column == "additional_image_link" {
+			value = strings.Join(additional, ",")
+		}
+		tsvBuf.WriteString(sanitizeTSV(value))
+	}
+	tsvBuf.WriteByte('\n')
+	item.tsv = tsvBuf.Bytes()
+
+	return item
+}
+
+func (f *productFeed) imageURL(url string) string {
+	if f.cfg.FeedImageBaseURL == "" || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return url
+	}
+	return strings.TrimSuffix(f.cfg.FeedImageBaseURL, "/") + "/" + strings.TrimPrefix(url, "/")
+}
+
+// price 优先使用 Feed 币种的价格，商品没有该币种价格时使用基础价格
+func (f *productFeed) price(p *models.ProductDetail) models.Money {
+	currency := strings.ToUpper(f.cfg.FeedCurrency)
+	if p.Price.Currency == currency {
+		return p.Price
+	}
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price
+		}
+	}
+	return p.Price
+}
+
+func (f *productFeed) mappedValue(p *models.ProductDetail, source string) string {
+	switch {
+	case source == "sku":
+		return p.SKU
+	case source == "gtin":
+		return p.GTIN
+	case source == "category":
+		return p.CategoryName
+	case strings.HasPrefix(source, "const:"):
+		return strings.TrimPrefix(source, "const:")
+	case strings.HasPrefix(source, "attr:"):
+		name := strings.TrimPrefix(source, "attr:")
+		for _, attr := range p.Attributes {
+			if strings.EqualFold(attr.Name, name) {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
+func writeFeedXMLElement(buf *bytes.Buffer, name, value string) {
+	buf.WriteString("<g:" + name + ">")
+	_ = xml.EscapeText(buf, []byte(value))
+	buf.WriteString("</g:" + name + ">")
+}
+
+func sanitizeTSV(value string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(value)
+}
+
+// buildAll 全量生成 Feed 条目，不修改缓存
+func (f *productFeed) buildAll() (map[int]*feedItem, error) {
+	items := map[int]*feedItem{}
+	err := streamProducts(" AND p.status = ?", []interface{}{models.ProductStatusPublished}, func(p *models.ProductDetail) error {
+		items[p.ID] = f.buildItem(p)
+		return nil
+	}, func() error { return nil })
+	return items, err
+}
+
+func (f *productFeed) replaceItems(items map[int]*feedItem) {
+	f.items = items
+	f.loaded = true
+	f.invalidate()
+}
+
+// refresh 重新加载指定商品的条目，已删除或未发布的商品从 Feed 中移除
+func (f *productFeed) refresh(productIDs []int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.loaded {
+		return nil
+	}
+
+	products, err := loadProductDetailsByID(database.DB, productIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range productIDs {
+		delete(f.items, id)
+	}
+	for i := range products {
+		if products[i].Status == models.ProductStatusPublished {
+			f.items[products[i].ID] = f.buildItem(&products[i])
+		}
+	}
+	f.invalidate()
+	return nil
+}
+
+func (f *productFeed) invalidate() {
+	f.xmlDoc = nil
+	f.tsvDoc = nil
+	f.generatedAt = time.Now()
+}
+
+func (f *productFeed) sortedItems() []*feedItem {
+	items := make([]*feedItem, 0, len(f.items))
+	for _, item := range f.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// document 返回缓存的 Feed 文档，首次访问时全量构建
+func (f *productFeed) document(format string) ([]byte, time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.loaded {
+		items, err := f.buildAll()
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		f.replaceItems(items)
+	}
+
+	switch format {
+	case "xml":
+		if f.xmlDoc == nil {
+			var buf bytes.Buffer
+			buf.WriteString(xml.Header)
+			buf.WriteString(`<rss version="2.0" xmlns:g="http://base.google.com/ns/1.0">` + "\n<channel>\n")
+			buf.WriteString("<title>")
+			_ = xml.EscapeText(&buf, []byte(f.cfg.FeedTitle))
+			buf.WriteString("</title>\n<link>")
+			_ = xml.EscapeText(&buf, []byte(f.cfg.FeedSiteURL))
+			buf.WriteString("</link>\n<description>")
+			_ = xml.EscapeText(&buf, []byte(f.cfg.FeedTitle))
+			buf.WriteString("</description>\n")
+			for _, item := range f.sortedItems() {
+				buf.Write(item.xml)
+			}
+			buf.WriteString("</channel>\n</rss>\n")
+			f.xmlDoc = buf.Bytes()
+		}
+		return f.xmlDoc, f.generatedAt, nil
+	default:
+		if f.tsvDoc == nil {
+			var buf bytes.Buffer
+			buf.WriteString(strings.Join(f.columns(), "\t") + "\n")
+			for _, item := range f.sortedItems() {
+				buf.Write(item.tsv)
+			}
+			f.tsvDoc = buf.Bytes()
+		}
+		return f.tsvDoc, f.generatedAt, nil
+	}
+}
+
+// HandleFeedEvent 根据商品事件增量刷新 Feed
+func HandleFeedEvent(event models.ProductEvent) {
+	var ids []int
+	switch event.EventType {
+	case models.EventCategoryCreated:
+		return
+	case models.EventProductsImported:
+		ids = event.ProductIDs
+	default:
+		if event.ProductID > 0 {
+			ids = []int{event.ProductID}
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	if err := feed.refresh(ids); err != nil {
+		log.Printf("Failed to refresh feed for products %v: %v", ids, err)
+	}
+}
+
+// StartFeedRefresher 定期全量重建 Feed，作为事件丢失时的兜底
+func StartFeedRefresher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		feed.mu.Lock()
+		loaded := feed.loaded
+		feed.mu.Unlock()
+		if !loaded {
+			continue
+		}
+
+		// 重建期间继续使用旧数据提供服务
+		items, err := feed.buildAll()
+		if err != nil {
+			log.Printf("Failed to rebuild product feed: %v", err)
+			continue
+		}
+		feed.mu.Lock()
+		feed.replaceItems(items)
+		feed.mu.Unlock()
+	}
+}
+
+func serveFeed(c *gin.Context, format, contentType string) {
+	doc, generatedAt, err := feed.document(format)
+	if err != nil {
+		log.Printf("Failed to generate product feed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate feed"})
+		return
+	}
+
+	c.Header("Last-Modified", generatedAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, contentType, doc)
+}
+
+// GetGoogleFeedXML 输出 Google Merchant Center RSS 2.0 格式的商品 Feed
+func GetGoogleFeedXML(c *gin.Context) {
+	serveFeed(c, "xml", "application/xml; charset=utf-8")
+}
+
+// GetGoogleFeedTSV 输出 Google Merchant Center TSV 格式的商品 Feed
+func GetGoogleFeedTSV(c *gin.Context) {
+	serveFeed(c, "tsv", "text/tab-separated-values; charset=utf-8")
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateGTIN 校验条码并规范化为 GTIN-14，空条码不校验
+func validateGTIN(gtin *string) error {
+	if *gtin == "" {
+		return nil
+	}
+	normalized, err := models.NormalizeGTIN(*gtin)
+	if err != nil {
+		return err
+	}
+	*gtin = normalized
+	return nil
+}
+
+// findGTINConflict 查找使用该条码的其他未删除商品，没有时返回 0
+func findGTINConflict(q querier, gtin string, excludeID int) (int, error) {
+	if gtin == "" {
+		return 0, nil
+	}
+	var id int
+	err := q.QueryRow(
+		"SELECT id FROM products WHERE gtin = ? AND deleted_at IS NULL AND id <> ? ORDER BY id LIMIT 1",
+		gtin, excludeID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// respondGTINConflict 检查条码是否被其他商品使用，冲突时返回 409 及冲突的商品ID，已写入响应时返回 true
+func respondGTINConflict(c *gin.Context, q querier, gtin string, excludeID int) bool {
+	conflictID, err := findGTINConflict(q, gtin, excludeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return true
+	}
+	if conflictID > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "GTIN already exists", "conflicting_product_id": conflictID})
+		return true
+	}
+	return false
+}
+
+// GetProductByBarcode 按条码获取商品，GET /products/barcode/:code，支持 GTIN-8/12/13/14
+func GetProductByBarcode(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("get_by_barcode", status)
+	}()
+
+	gtin, err := models.NormalizeGTIN(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var productID int
+	err = database.DB.QueryRow(
+		"SELECT id FROM products WHERE gtin = ? AND deleted_at IS NULL ORDER BY id LIMIT 1", gtin,
+	).Scan(&productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	renderProductDetail(c, productID)
+}
@@ -0,0 +1,935 @@
+package controllers
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"product-service/config"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"product-service/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// NDJSON 单行最大长度
+const maxImportLineBytes = 1 << 20
+
+// 执行中的导入任务定期更新心跳，超过 importStaleAfter 没有心跳的任务视为已中断
+const (
+	importHeartbeatInterval = 30 * time.Second
+	importStaleAfter        = 5 * time.Minute
+)
+
+// importRow 导入文件中的一行，Row 为数据行序号（从1开始，不含CSV表头）
+type importRow struct {
+	Row     int
+	Product models.Product
+	Err     error
+}
+
+// importReader 逐行读取导入文件，读完时返回 io.EOF
+type importReader interface {
+	Next() (importRow, error)
+}
+
+type csvImportReader struct {
+	reader *csv.Reader
+	header map[string]int
+	row    int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	header := make(map[string]int, len(columns))
+	for i, column := range columns {
+		header[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))] = i
+	}
+	for _, required := range []string{"name", "sku"} {
+		if _, ok := header[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing required column %q", required)
+		}
+	}
+
+	return &csvImportReader{reader: reader, header: header}, nil
+}
+
+func (r *csvImportReader) Next() (importRow, error) {
+	record, err := r.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return importRow{}, io.EOF
+	}
+
+	r.row++
+	row := importRow{Row: r.row}
+	if err != nil {
+		// 单行格式错误不影响后续行
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			row.Err = err
+			return row, nil
+		}
+		return row, err
+	}
+
+	row.Product, row.Err = productFromCSVRecord(r.header, record)
+	return row, nil
+}
+
+func productFromCSVRecord(header map[string]int, record []string) (models.Product, error) {
+	var product models.Product
+
+	get := func(column string) string {
+		if i, ok := header[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	product.Name = get("name")
+	product.Description = get("description")
+	product.SKU = get("sku")
+	product.GTIN = get("gtin")
+	product.ImageURL = get("image_url")
+	product.Status = strings.ToLower(get("status"))
+
+	var err error
+	if product.Weight, err = models.ParseWeight(get("weight")); err != nil {
+		return product, err
+	}
+	if product.Dimensions, err = models.ParseDimensions(get("dimensions")); err != nil {
+		return product, err
+	}
+	if value := get("publish_at"); value != "" {
+		publishAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return product, fmt.Errorf("invalid publish_at %q", value)
+		}
+		product.PublishAt = &publishAt
+	}
+	if value := get("price"); value != "" {
+		currency := get("currency")
+		if currency == "" {
+			currency = models.DefaultCurrency
+		}
+		if product.Price, err = models.ParseMoney(value, currency); err != nil {
+			return product, fmt.Errorf("invalid price %q: %v", value, err)
+		}
+	}
+	if value := get("stock"); value != "" {
+		if product.Stock, err = strconv.Atoi(value); err != nil {
+			return product, fmt.Errorf("invalid stock %q", value)
+		}
+	}
+	if value := get("category_id"); value != "" {
+		if product.CategoryID, err = strconv.Atoi(value); err != nil {
+			return product, fmt.Errorf("invalid category_id %q", value)
+		}
+	}
+
+	return product, nil
+}
+
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	row     int
+}
+
+func newNDJSONImportReader(r io.Reader) *ndjsonImportReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineBytes)
+	return &ndjsonImportReader{scanner: scanner}
+}
+
+func (r *ndjsonImportReader) Next() (importRow, error) {
+	for r.scanner.Scan() {
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		r.row++
+		row := importRow{Row: r.row}
+		if err := json.Unmarshal([]byte(line), &row.Product); err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %v", err)
+		}
+		return row, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return importRow{}, err
+	}
+	return importRow{}, io.EOF
+}
+
+func openImportReader(file io.Reader, format string) (importReader, error) {
+	switch format {
+	case models.ImportFormatCSV:
+		return newCSVImportReader(file)
+	case models.ImportFormatNDJSON:
+		return newNDJSONImportReader(file), nil
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+// countImportRows 预先统计数据行数，用于展示进度
+func countImportRows(path, format string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader, err := openImportReader(file, format)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for {
+		if _, err := reader.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return total, nil
+			}
+			return total, err
+		}
+		total++
+	}
+}
+
+// detectImportFormat 依次根据 format 参数、文件扩展名和 Content-Type 判断导入格式
+func detectImportFormat(format, filename, contentType string) string {
+	switch strings.ToLower(format) {
+	case models.ImportFormatCSV, models.ImportFormatNDJSON:
+		return strings.ToLower(format)
+	case "":
+	default:
+		return ""
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return models.ImportFormatCSV
+	case ".ndjson", ".jsonl":
+		return models.ImportFormatNDJSON
+	}
+
+	switch contentType {
+	case "text/csv":
+		return models.ImportFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return models.ImportFormatNDJSON
+	}
+	return ""
+}
+
+// ImportProducts 上传CSV或NDJSON文件，创建后台导入任务
+func ImportProducts(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("import", status)
+	}()
+	cfg := config.LoadConfig()
+
+	eventMode := c.DefaultQuery("events", cfg.ImportEventMode)
+	if eventMode != models.ImportEventsPerProduct && eventMode != models.ImportEventsBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "events must be per_product or batch"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.ImportMaxUploadBytes)
+
+	var source io.Reader
+	filename := ""
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing upload file field 'file'"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload file"})
+			return
+		}
+		defer file.Close()
+		source = file
+		filename = fileHeader.Filename
+	} else {
+		source = c.Request.Body
+	}
+
+	format := detectImportFormat(c.Query("format"), filename, c.ContentType())
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to determine import format, expected csv or ndjson"})
+		return
+	}
+
+	// 上传内容先落盘，后台任务逐行读取，避免整个文件驻留内存
+	tmp, err := os.CreateTemp("", "product-import-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store upload"})
+		return
+	}
+	if _, err := io.Copy(tmp, source); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload: " + err.Error()})
+		return
+	}
+	tmp.Close()
+
+	userID := c.GetInt("userID")
+	result, err := database.DB.Exec(`
+		INSERT INTO import_jobs (status, format, event_mode, created_by)
+		VALUES (?, ?, ?, ?)
+	`, models.ImportStatusPending, format, eventMode, userID)
+	if err != nil {
+		os.Remove(tmp.Name())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import job"})
+		return
+	}
+
+	jobID, _ := result.LastInsertId()
+	middlewares.SetAuditEntity(c, "import_job", jobID)
+
+	batchSize := cfg.ImportBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	importer := &productImporter{
+		jobID:      int(jobID),
+		eventMode:  eventMode,
+		actorID:    userID,
+		batchSize:  batchSize,
+		categories: map[int]bool{},
+	}
+	go importer.run(tmp.Name(), format)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id": jobID,
+		"status": models.ImportStatusPending,
+	})
+}
+
+// canAccessImportJob 只有任务的创建者和管理员可以查看导入任务
+func canAccessImportJob(c *gin.Context, createdBy int) bool {
+	return c.GetInt("userID") == createdBy || middlewares.IsAdmin(c)
+}
+
+// GetImportJob 查询导入任务进度
+func GetImportJob(c *gin.Context) {
+	jobID, err := strconv.Atoi(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	var job models.ImportJob
+	var startedAt, finishedAt sql.NullTime
+	err = database.DB.QueryRow(`
+		SELECT id, status, format, event_mode, total_rows, processed_rows, created_count,
+		       updated_count, failed_count, created_by, error, created_at, started_at, finished_at
+		FROM import_jobs
+		WHERE id = ?
+	`, jobID).Scan(
+		&job.ID, &job.Status, &job.Format, &job.EventMode, &job.TotalRows, &job.ProcessedRows,
+		&job.CreatedCount, &job.UpdatedCount, &job.FailedCount, &job.CreatedBy, &job.Error,
+		&job.CreatedAt, &startedAt, &finishedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !canAccessImportJob(c, job.CreatedBy) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ListImportJobErrors 分页查询导入任务的逐行错误报告
+func ListImportJobErrors(c *gin.Context) {
+	jobID, err := strconv.Atoi(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var createdBy int
+	err = database.DB.QueryRow("SELECT created_by FROM import_jobs WHERE id = ?", jobID).Scan(&createdBy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !canAccessImportJob(c, createdBy) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+
+	var total int
+	err = database.DB.QueryRow("SELECT COUNT(*) FROM import_job_errors WHERE job_id = ?", jobID).Scan(&total)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT row_no, sku, error
+		FROM import_job_errors
+		WHERE job_id = ?
+		ORDER BY row_no
+		LIMIT ? OFFSET ?
+	`, jobID, pagination.PageSize, (pagination.Page-1)*pagination.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	rowErrors := []models.ImportRowError{}
+	for rows.Next() {
+		var rowErr models.ImportRowError
+		if err := rows.Scan(&rowErr.Row, &rowErr.SKU, &rowErr.Error); err != nil {
+			log.Printf("Error scanning import error: %v", err)
+			continue
+		}
+		rowErrors = append(rowErrors, rowErr)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"errors":     rowErrors,
+		"total":      total,
+		"page":       pagination.Page,
+		"page_size":  pagination.PageSize,
+		"total_page": utils.CalculateTotalPages(total, pagination.PageSize),
+	})
+}
+
+// productImporter 执行单个导入任务
+type productImporter struct {
+	jobID      int
+	eventMode  string
+	actorID    int
+	batchSize  int
+	categories map[int]bool
+	importedID []int
+}
+
+func (im *productImporter) run(path, format string) {
+	defer os.Remove(path)
+
+	// 统计行数前就开始心跳，避免大文件在统计期间被其他副本判定为中断的任务
+	result, err := database.DB.Exec(
+		"UPDATE import_jobs SET heartbeat_at = NOW() WHERE id = ? AND status = ?",
+		im.jobID, models.ImportStatusPending,
+	)
+	if err != nil {
+		log.Printf("Failed to start import job %d: %v", im.jobID, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		log.Printf("Import job %d is no longer pending, skipping", im.jobID)
+		return
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go im.heartbeat(stop)
+
+	total, err := countImportRows(path, format)
+	if err != nil {
+		im.fail(err)
+		return
+	}
+
+	// 状态切换都以当前状态为条件，任务已被判定为中断时不再继续
+	result, err = database.DB.Exec(`
+		UPDATE import_jobs SET status = ?, total_rows = ?, started_at = NOW(), heartbeat_at = NOW()
+		WHERE id = ? AND status = ?
+	`, models.ImportStatusRunning, total, im.jobID, models.ImportStatusPending)
+	if err != nil {
+		log.Printf("Failed to start import job %d: %v", im.jobID, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		log.Printf("Import job %d was marked as failed before it started", im.jobID)
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		im.fail(err)
+		return
+	}
+	defer file.Close()
+
+	reader, err := openImportReader(file, format)
+	if err != nil {
+		im.fail(err)
+		return
+	}
+
+	batch := make([]importRow, 0, im.batchSize)
+	for {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			im.fail(err)
+			return
+		}
+
+		batch = append(batch, row)
+		if len(batch) >= im.batchSize {
+			if !im.processNextBatch(batch) {
+				return
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 && !im.processNextBatch(batch) {
+		return
+	}
+
+	if im.eventMode == models.ImportEventsBatch && len(im.importedID) > 0 && rabbitMQ != nil {
+		sendProductEvent(models.EventProductsImported, 0, im.importedID)
+	}
+
+	result, err = database.DB.Exec(`
+		UPDATE import_jobs SET status = ?, finished_at = NOW() WHERE id = ? AND status = ?
+	`, models.ImportStatusCompleted, im.jobID, models.ImportStatusRunning)
+	if err != nil {
+		log.Printf("Failed to complete import job %d: %v", im.jobID, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		log.Printf("Import job %d was marked as failed while running", im.jobID)
+	}
+}
+
+// processNextBatch 任务仍在运行时处理下一批并返回 true；任务已被标记为失败时返回 false，
+// 批量事件模式下先为已导入的商品发送汇总事件
+func (im *productImporter) processNextBatch(batch []importRow) bool {
+	var status string
+	if err := database.DB.QueryRow("SELECT status FROM import_jobs WHERE id = ?", im.jobID).Scan(&status); err != nil {
+		log.Printf("Failed to check import job %d status: %v", im.jobID, err)
+		return false
+	}
+	if status != models.ImportStatusRunning {
+		log.Printf("Import job %d is %s, stopping", im.jobID, status)
+		if im.eventMode == models.ImportEventsBatch && len(im.importedID) > 0 && rabbitMQ != nil {
+			sendProductEvent(models.EventProductsImported, 0, im.importedID)
+		}
+		return false
+	}
+	im.processBatch(batch)
+	return true
+}
+
+// heartbeat 任务执行期间定期更新心跳时间，直到 stop 关闭
+func (im *productImporter) heartbeat(stop <-chan struct{}) {
+	ticker := time.NewTicker(importHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_, err := database.DB.Exec(
+				"UPDATE import_jobs SET heartbeat_at = NOW() WHERE id = ? AND status IN (?, ?)",
+				im.jobID, models.ImportStatusPending, models.ImportStatusRunning,
+			)
+			if err != nil {
+				log.Printf("Failed to update import job %d heartbeat: %v", im.jobID, err)
+			}
+		}
+	}
+}
+
+// StartStaleImportJobMonitor 启动时及之后定期将心跳超时的未完成任务标记为失败，
+// 服务重启后这些任务不会再继续执行，上传的临时文件也已丢失
+func StartStaleImportJobMonitor() {
+	failStaleImportJobs()
+
+	ticker := time.NewTicker(importStaleAfter)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		failStaleImportJobs()
+	}
+}
+
+func failStaleImportJobs() {
+	result, err := database.DB.Exec(`
+		UPDATE import_jobs
+		SET status = ?, error = ?, finished_at = NOW()
+		WHERE status IN (?, ?)
+		  AND COALESCE(heartbeat_at, created_at) < DATE_SUB(NOW(), INTERVAL ? SECOND)
+	`, models.ImportStatusFailed, "Import interrupted by service restart",
+		models.ImportStatusPending, models.ImportStatusRunning, int(importStaleAfter.Seconds()))
+	if err != nil {
+		log.Printf("Failed to mark stale import jobs as failed: %v", err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("Marked %d stale import jobs as failed", n)
+	}
+}
+
+func (im *productImporter) fail(cause error) {
+	log.Printf("Import job %d failed: %v", im.jobID, cause)
+	message := cause.Error()
+	if len(message) > 1000 {
+		message = message[:1000]
+	}
+	_, err := database.DB.Exec(`
+		UPDATE import_jobs SET status = ?, error = ?, finished_at = NOW() WHERE id = ? AND status IN (?, ?)
+	`, models.ImportStatusFailed, message, im.jobID, models.ImportStatusPending, models.ImportStatusRunning)
+	if err != nil {
+		log.Printf("Failed to mark import job %d as failed: %v", im.jobID, err)
+	}
+}
+
+// validate 使用与 models.Product 绑定相同的校验规则
+func (im *productImporter) validate(product *models.Product) error {
+	if err := validateSKU(&product.SKU); err != nil {
+		// 已有商品按 SKU 更新时 SKU 不变，不要求符合当前的格式规则
+		existingID, findErr := findSKUConflict(database.DB, product.SKU, 0)
+		if findErr != nil {
+			return errors.New("failed to verify sku")
+		}
+		if existingID == 0 {
+			return err
+		}
+	}
+	if product.SKU == "" {
+		return errors.New("sku is required for import")
+	}
+	if err := validateGTIN(&product.GTIN); err != nil {
+		return err
+	}
+	if err := validateProductMeasures(product); err != nil {
+		return err
+	}
+	if err := binding.Validator.ValidateStruct(product); err != nil {
+		return err
+	}
+	if err := validateProductPrice(*product); err != nil {
+		return err
+	}
+	// 状态只对新建的商品生效，更新已有商品时保持原状态
+	if err := normalizeNewProductStatus(product, time.Now()); err != nil {
+		return err
+	}
+
+	valid, cached := im.categories[product.CategoryID]
+	if !cached {
+		err := database.DB.QueryRow(
+			"SELECT EXISTS(SELECT 1 FROM categories WHERE id = ?)",
+			product.CategoryID,
+		).Scan(&valid)
+		if err != nil {
+			return errors.New("failed to verify category")
+		}
+		im.categories[product.CategoryID] = valid
+	}
+	if !valid {
+		return errors.New("invalid category ID")
+	}
+	return nil
+}
+
+// importedProduct 批次中成功写入的商品
+type importedProduct struct {
+	product models.Product
+	created bool
+}
+
+// processBatch 在一个事务中按SKU写入一批商品，单行失败通过保存点回滚，不影响同批其他行
+func (im *productImporter) processBatch(batch []importRow) {
+	var rowErrors []models.ImportRowError
+	var valid []importRow
+
+	for _, row := range batch {
+		if row.Err == nil {
+			row.Err = im.validate(&row.Product)
+		}
+		if row.Err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row.Row, SKU: row.Product.SKU, Error: row.Err.Error()})
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	var imported []importedProduct
+	if len(valid) > 0 {
+		var batchErrors []models.ImportRowError
+		imported, batchErrors = im.writeBatch(valid)
+		rowErrors = append(rowErrors, batchErrors...)
+	}
+
+	created, updated := 0, 0
+	for _, item := range imported {
+		if item.created {
+			created++
+		} else {
+			updated++
+		}
+	}
+
+	_, err := database.DB.Exec(`
+		UPDATE import_jobs
+		SET processed_rows = processed_rows + ?, created_count = created_count + ?,
+		    updated_count = updated_count + ?, failed_count = failed_count + ?
+		WHERE id = ?
+	`, len(batch), created, updated, len(rowErrors), im.jobID)
+	if err != nil {
+		log.Printf("Failed to update import job %d progress: %v", im.jobID, err)
+	}
+
+	im.saveRowErrors(rowErrors)
+
+	importedIDs := make([]int, len(imported))
+	for i, item := range imported {
+		importedIDs[i] = item.product.ID
+	}
+	reindexProducts(importedIDs...)
+
+	for _, item := range imported {
+		if im.eventMode == models.ImportEventsBatch {
+			im.importedID = append(im.importedID, item.product.ID)
+			continue
+		}
+		if rabbitMQ != nil {
+			eventType := models.EventProductUpdated
+			if item.created {
+				eventType = models.EventProductCreated
+			}
+			sendProductEvent(eventType, item.product.ID, item.product)
+			if item.created && item.product.Status == models.ProductStatusPublished {
+				sendProductEvent(models.EventProductPublished, item.product.ID, item.product)
+			}
+		}
+	}
+}
+
+func (im *productImporter) writeBatch(rows []importRow) ([]importedProduct, []models.ImportRowError) {
+	batchFailed := func(err error) ([]importedProduct, []models.ImportRowError) {
+		var rowErrors []models.ImportRowError
+		for _, row := range rows {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row.Row, SKU: row.Product.SKU, Error: err.Error()})
+		}
+		return nil, rowErrors
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return batchFailed(errors.New("could not start transaction"))
+	}
+	defer tx.Rollback()
+
+	var imported []importedProduct
+	var rowErrors []models.ImportRowError
+	for _, row := range rows {
+		if _, err := tx.Exec("SAVEPOINT import_row"); err != nil {
+			return batchFailed(err)
+		}
+
+		product := row.Product
+		created, err := upsertProductBySKU(tx, &product, im.actorID)
+		if err != nil {
+			if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT import_row"); rbErr != nil {
+				return batchFailed(rbErr)
+			}
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row.Row, SKU: product.SKU, Error: err.Error()})
+			continue
+		}
+
+		if _, err := tx.Exec("RELEASE SAVEPOINT import_row"); err != nil {
+			return batchFailed(err)
+		}
+		imported = append(imported, importedProduct{product: product, created: created})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return batchFailed(errors.New("transaction commit failed"))
+	}
+	return imported, rowErrors
+}
+
+// upsertProductBySKU 按SKU更新未删除的商品，不存在时新建，返回是否新建
+func upsertProductBySKU(tx *sql.Tx, product *models.Product, actorID int) (bool, error) {
+	var productID int
+	var status string
+	var publishAt, publishedAt sql.NullTime
+	err := tx.QueryRow(
+		"SELECT id, status, publish_at, published_at FROM products WHERE sku = ? AND deleted_at IS NULL ORDER BY id LIMIT 1 FOR UPDATE",
+		product.SKU,
+	).Scan(&productID, &status, &publishAt, &publishedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, errors.New("database error")
+	}
+	conflictID, gtinErr := findGTINConflict(tx, product.GTIN, productID)
+	if gtinErr != nil {
+		return false, errors.New("database error")
+	}
+	if conflictID > 0 {
+		return false, fmt.Errorf("gtin already exists (product %d)", conflictID)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		result, err := tx.Exec(
+			`INSERT INTO products
+			(name, description, price, price_amount, currency, stock, category_id, sku, gtin, image_url,
+			 weight, weight_unit, length, width, height, dimension_unit, status, publish_at, published_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			product.Name, product.Description, product.Price.Decimal(), product.Price.Amount, product.Price.Currency, product.Stock,
+			product.CategoryID, product.SKU, product.GTIN, product.ImageURL,
+			product.Weight.Value, product.Weight.Unit, product.Dimensions.Length, product.Dimensions.Width, product.Dimensions.Height, product.Dimensions.Unit, product.Status, product.PublishAt, product.PublishedAt,
+		)
+		if err != nil {
+			// 其他请求并发创建了相同 SKU 或条码的商品
+			if isDuplicateKeyError(err) {
+				return false, errors.New("sku or gtin already exists")
+			}
+			return false, errors.New("failed to create product")
+		}
+		id, _ := result.LastInsertId()
+		product.ID = int(id)
+		if err := checkImportRequiredAttributes(tx, product); err != nil {
+			return false, err
+		}
+
+		if _, err := recordRevision(tx, product.ID, models.RevisionActionCreate, actorID); err != nil {
+			return false, errors.New("failed to record revision")
+		}
+		return true, nil
+	}
+
+	product.ID = productID
+	product.Status, product.PublishAt, product.PublishedAt = status, nil, nil
+	if publishAt.Valid {
+		product.PublishAt = &publishAt.Time
+	}
+	if publishedAt.Valid {
+		product.PublishedAt = &publishedAt.Time
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		return false, errors.New("failed to record revision")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE products
+		SET name = ?, description = ?, price = ?, price_amount = ?, currency = ?, stock = ?,
+		    category_id = ?, gtin = ?, image_url = ?,
+		    weight = ?, weight_unit = ?, length = ?, width = ?, height = ?, dimension_unit = ?, updated_at = NOW()
+		WHERE id = ?
+	`,
+		product.Name, product.Description, product.Price.Decimal(), product.Price.Amount, product.Price.Currency, product.Stock,
+		product.CategoryID, product.GTIN, product.ImageURL,
+		product.Weight.Value, product.Weight.Unit, product.Dimensions.Length, product.Dimensions.Width, product.Dimensions.Height, product.Dimensions.Unit,
+		productID)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return false, errors.New("gtin already exists")
+		}
+		return false, errors.New("failed to update product")
+	}
+	if err := dropBaseCurrencyPrice(tx, productID, product.Price.Currency); err != nil {
+		return false, errors.New("failed to update product")
+	}
+	if err := checkImportRequiredAttributes(tx, product); err != nil {
+		return false, err
+	}
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionUpdate, actorID); err != nil {
+		return false, errors.New("failed to record revision")
+	}
+	return false, nil
+}
+
+// checkImportRequiredAttributes 已发布或定时发布的商品须已设置所在分类要求的全部属性，
+// 导入文件不包含属性，新建的非草稿商品和移动到其他分类的已发布商品都可能缺少必填属性
+func checkImportRequiredAttributes(tx *sql.Tx, product *models.Product) error {
+	if product.Status != models.ProductStatusPublished && product.Status != models.ProductStatusScheduled {
+		return nil
+	}
+	missing, err := missingRequiredAttributes(tx, product.ID, product.CategoryID)
+	if err != nil {
+		return errors.New("database error")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required attributes for a %s product: %s", product.Status, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (im *productImporter) saveRowErrors(rowErrors []models.ImportRowError) {
+	if len(rowErrors) == 0 {
+		return
+	}
+
+	query := "INSERT INTO import_job_errors (job_id, row_no, sku, error) VALUES "
+	args := make([]interface{}, 0, len(rowErrors)*4)
+	for i, rowErr := range rowErrors {
+		if i > 0 {
+			query += ", "
+		}
+		query += "(?, ?, ?, ?)"
+		message := rowErr.Error
+		if len(message) > 1000 {
+			message = message[:1000]
+		}
+		args = append(args, im.jobID, rowErr.Row, rowErr.SKU, message)
+	}
+
+	if _, err := database.DB.Exec(query, args...); err != nil {
+		log.Printf("Failed to save import errors for job %d: %v", im.jobID, err)
+	}
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateProductPrice 校验商品基础价格，替代原先 price 字段的 binding:"required"
+func validateProductPrice(p models.Product) error {
+	if !p.Price.IsPositive() {
+		return errors.New("price must be greater than zero")
+	}
+	return nil
+}
+
+// productPriceRequest 设置单个币种价格的请求体，amount 为该币种的十进制金额
+type productPriceRequest struct {
+	Amount string `json:"amount" binding:"required"`
+}
+
+// SetProductPrice 设置商品在某个币种下的价格，基础币种的价格通过修改商品设置
+func SetProductPrice(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("set_price", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	currency, err := models.NormalizeCurrency(c.Param("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req productPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	price, err := models.ParseMoney(req.Amount, currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !price.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be greater than zero"})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	baseCurrency, err := lockProductCurrency(tx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if currency == baseCurrency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Base currency price must be set by updating the product"})
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO product_prices (product_id, currency, amount)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE amount = VALUES(amount)
+	`, productID, price.Currency, price.Amount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set price"})
+		return
+	}
+
+	if err := commitPriceChange(c, tx, productID, models.RevisionActionSetPrice); err != nil {
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	c.JSON(http.StatusOK, gin.H{"product_id": productID, "price": price})
+}
+
+// DeleteProductPrice 删除商品在某个币种下的价格
+func DeleteProductPrice(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("remove_price", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	currency, err := models.NormalizeCurrency(c.Param("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := lockProductCurrency(tx, productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	result, err := tx.Exec("DELETE FROM product_prices WHERE product_id = ? AND currency = ?", productID, currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove price"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price not found"})
+		return
+	}
+
+	if err := commitPriceChange(c, tx, productID, models.RevisionActionRemovePrice); err != nil {
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	c.JSON(http.StatusOK, gin.H{"message": "Price removed"})
+}
+
+// lockProductCurrency 锁定商品行并返回其基础币种
+func lockProductCurrency(tx *sql.Tx, productID int) (string, error) {
+	if err := lockProduct(tx, productID); err != nil {
+		return "", err
+	}
+	var currency string
+	err := tx.QueryRow("SELECT currency FROM products WHERE id = ?", productID).Scan(&currency)
+	return currency, err
+}
+
+// dropBaseCurrencyPrice 基础币种变更后删除价格表中与之重复的价格
+func dropBaseCurrencyPrice(tx *sql.Tx, productID int, currency string) error {
+	_, err := tx.Exec("DELETE FROM product_prices WHERE product_id = ? AND currency = ?", productID, currency)
+	return err
+}
+
+// commitPriceChange 记录修订并提交价格变更，随后通知其他服务并刷新搜索索引。
+// action 为空时不记录修订，用于不在修订快照中的价格数据（如阶梯价格）。出错时已写入响应
+func commitPriceChange(c *gin.Context, tx *sql.Tx, productID int, action string) error {
+	if _, err := tx.Exec("UPDATE products SET updated_at = NOW() WHERE id = ?", productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
+		return err
+	}
+
+	if action != "" {
+		if _, err := recordRevision(tx, productID, action, c.GetInt("userID")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+			return err
+		}
+	}
+
+	product, err := loadProductDetail(tx, productID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return err
+	}
+
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventProductUpdated, productID, product.Product)
+	}
+	reindexProducts(productID)
+	return nil
+}
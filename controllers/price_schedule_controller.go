@@ -0,0 +1,718 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 每次检查最多处理的价格计划数量
+const priceScheduleBatchSize = 100
+
+const priceScheduleColumns = `id, product_id, currency, amount, starts_at, ends_at, status,
+	previous_amount, created_by, created_at, activated_at, ended_at`
+
+func scanPriceSchedule(row scanner, s *models.PriceSchedule) error {
+	var endsAt, activatedAt, endedAt sql.NullTime
+	var previous sql.NullInt64
+	err := row.Scan(
+		&s.ID, &s.ProductID, &s.Price.Currency, &s.Price.Amount, &s.StartsAt, &endsAt, &s.Status,
+		&previous, &s.CreatedBy, &s.CreatedAt, &activatedAt, &endedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if endsAt.Valid {
+		s.EndsAt = &endsAt.Time
+	}
+	if activatedAt.Valid {
+		s.ActivatedAt = &activatedAt.Time
+	}
+	if endedAt.Valid {
+		s.EndedAt = &endedAt.Time
+	}
+	if previous.Valid {
+		s.PreviousPrice = &models.Money{Amount: previous.Int64, Currency: s.Price.Currency}
+	}
+	return nil
+}
+
+// lockScheduledProduct 锁定商品行并返回其基础币种，includeDeleted 为 true 时包含已软删除的商品
+func lockScheduledProduct(tx *sql.Tx, productID int, includeDeleted bool) (string, error) {
+	query := "SELECT currency FROM products WHERE id = ?"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	var currency string
+	err := tx.QueryRow(query+" FOR UPDATE", productID).Scan(&currency)
+	return currency, err
+}
+
+// listedPrice 商品在指定币种下直接设置的价格，没有时返回 nil
+func listedPrice(tx *sql.Tx, productID int, baseCurrency, currency string) (*models.Money, error) {
+	var amount int64
+	var err error
+	if currency == baseCurrency {
+		err = tx.QueryRow("SELECT price_amount FROM products WHERE id = ?", productID).Scan(&amount)
+	} else {
+		err = tx.QueryRow(
+			"SELECT amount FROM product_prices WHERE product_id = ? AND currency = ?",
+			productID, currency,
+		).Scan(&amount)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.Money{Amount: amount, Currency: currency}, nil
+}
+
+// setListedPrice 设置商品在指定币种下的价格，price 为 nil 时删除价格表中的价格
+func setListedPrice(tx *sql.Tx, productID int, baseCurrency string, currency string, price *models.Money) error {
+	var err error
+	switch {
+	case currency == baseCurrency && price != nil:
+		_, err = tx.Exec(
+			"UPDATE products SET price = ?, price_amount = ?, updated_at = NOW() WHERE id = ?",
+			price.Decimal(), price.Amount, productID,
+		)
+		return err
+	case currency == baseCurrency:
+		return errors.New("base currency price cannot be removed")
+	case price != nil:
+		_, err = tx.Exec(`
+			INSERT INTO product_prices (product_id, currency, amount)
+			VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE amount = VALUES(amount)
+		`, productID, currency, price.Amount)
+	default:
+		_, err = tx.Exec("DELETE FROM product_prices WHERE product_id = ? AND currency = ?", productID, currency)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE products SET updated_at = NOW() WHERE id = ?", productID)
+	return err
+}
+
+func sameMoney(a, b *models.Money) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// activatePriceSchedule 在事务内使计划价格生效，计划行须已锁定。商品已删除时跳过该计划
+func activatePriceSchedule(tx *sql.Tx, s *models.PriceSchedule, now time.Time) (*models.PriceChange, error) {
+	baseCurrency, err := lockScheduledProduct(tx, s.ProductID, false)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.Exec(
+			"UPDATE price_schedules SET status = ?, ended_at = ? WHERE id = ?",
+			models.PriceScheduleSkipped, now, s.ID,
+		)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureBaselineRevision(tx, s.ProductID); err != nil {
+		return nil, err
+	}
+
+	previous, err := listedPrice(tx, s.ProductID, baseCurrency, s.Price.Currency)
+	if err != nil {
+		return nil, err
+	}
+	price := s.Price
+	if err := setListedPrice(tx, s.ProductID, baseCurrency, s.Price.Currency, &price); err != nil {
+		return nil, err
+	}
+
+	var previousAmount interface{}
+	if previous != nil {
+		previousAmount = previous.Amount
+	}
+	_, err = tx.Exec(
+		"UPDATE price_schedules SET status = ?, previous_amount = ?, activated_at = ? WHERE id = ?",
+		models.PriceScheduleActive, previousAmount, now, s.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := recordRevision(tx, s.ProductID, models.RevisionActionScheduleStart, s.CreatedBy); err != nil {
+		return nil, err
+	}
+
+	if sameMoney(previous, &price) {
+		return nil, nil
+	}
+	return &models.PriceChange{
+		Currency:   s.Price.Currency,
+		OldPrice:   previous,
+		NewPrice:   &price,
+		ScheduleID: s.ID,
+		Reason:     models.RevisionActionScheduleStart,
+	}, nil
+}
+
+// finishPriceSchedule 在事务内结束已生效的计划并恢复生效前的价格，计划行须已锁定。
+// 生效期间价格被手动修改过时保留当前价格
+func finishPriceSchedule(tx *sql.Tx, s *models.PriceSchedule, status string, actorID int, now time.Time) (*models.PriceChange, error) {
+	_, err := tx.Exec("UPDATE price_schedules SET status = ?, ended_at = ? WHERE id = ?", status, now, s.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	baseCurrency, err := lockScheduledProduct(tx, s.ProductID, true)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := listedPrice(tx, s.ProductID, baseCurrency, s.Price.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if !sameMoney(current, &s.Price) {
+		log.Printf("Price of product %d in %s changed while schedule %d was active, keeping current price",
+			s.ProductID, s.Price.Currency, s.ID)
+		return nil, nil
+	}
+	// 基础币种可能在生效期间变更，此时无法删除基础价格，保留当前价格
+	if s.PreviousPrice == nil && s.Price.Currency == baseCurrency {
+		return nil, nil
+	}
+
+	if err := ensureBaselineRevision(tx, s.ProductID); err != nil {
+		return nil, err
+	}
+	if err := setListedPrice(tx, s.ProductID, baseCurrency, s.Price.Currency, s.PreviousPrice); err != nil {
+		return nil, err
+	}
+	if _, err := recordRevision(tx, s.ProductID, models.RevisionActionScheduleEnd, actorID); err != nil {
+		return nil, err
+	}
+
+	return &models.PriceChange{
+		Currency:   s.Price.Currency,
+		OldPrice:   current,
+		NewPrice:   s.PreviousPrice,
+		ScheduleID: s.ID,
+		Reason:     models.RevisionActionScheduleEnd,
+	}, nil
+}
+
+// priceChanged 价格计划提交后通知其他服务并刷新搜索索引
+func priceChanged(productID int, change *models.PriceChange) {
+	if change == nil {
+		return
+	}
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventPriceChanged, productID, *change)
+	}
+	reindexProducts(productID)
+}
+
+// StartPriceScheduler 定期使到期的价格计划生效或结束
+func StartPriceScheduler(interval time.Duration) {
+	runPriceSchedules()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		runPriceSchedules()
+	}
+}
+
+func runPriceSchedules() {
+	now := time.Now()
+
+	// 整个生效期都已错过的计划不再生效
+	_, err := database.DB.Exec(`
+		UPDATE price_schedules SET status = ?, ended_at = ?
+		WHERE status = ? AND ends_at IS NOT NULL AND ends_at <= ?
+	`, models.PriceScheduleSkipped, now, models.PriceSchedulePending, now)
+	if err != nil {
+		log.Printf("Failed to skip missed price schedules: %v", err)
+	}
+
+	// 先结束再生效，使首尾相接的计划按顺序切换
+	ended := transitionDueSchedules(`
+		SELECT id FROM price_schedules
+		WHERE status = ? AND ends_at IS NOT NULL AND ends_at <= ?
+		ORDER BY ends_at, id LIMIT ?
+	`, models.PriceScheduleActive, now, func(tx *sql.Tx, s *models.PriceSchedule) (*models.PriceChange, error) {
+		return finishPriceSchedule(tx, s, models.PriceScheduleEnded, s.CreatedBy, now)
+	})
+	started := transitionDueSchedules(`
+		SELECT id FROM price_schedules
+		WHERE status = ? AND starts_at <= ?
+		ORDER BY starts_at, id LIMIT ?
+	`, models.PriceSchedulePending, now, func(tx *sql.Tx, s *models.PriceSchedule) (*models.PriceChange, error) {
+		return activatePriceSchedule(tx, s, now)
+	})
+
+	if ended+started > 0 {
+		log.Printf("Price schedules: %d started, %d ended", started, ended)
+	}
+}
+
+// transitionDueSchedules 逐个处理到期的计划，每个计划一个事务；
+// 锁定后重新检查状态，多个副本同时运行时每个计划只处理一次
+func transitionDueSchedules(query, status string, now time.Time,
+	transition func(tx *sql.Tx, s *models.PriceSchedule) (*models.PriceChange, error)) int {
+	rows, err := database.DB.Query(query, status, now, priceScheduleBatchSize)
+	if err != nil {
+		log.Printf("Failed to query due price schedules: %v", err)
+		return 0
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	processed := 0
+	for _, id := range ids {
+		productID, change, err := func() (int, *models.PriceChange, error) {
+			tx, err := database.DB.Begin()
+			if err != nil {
+				return 0, nil, err
+			}
+			defer tx.Rollback()
+
+			var s models.PriceSchedule
+			err = scanPriceSchedule(tx.QueryRow(
+				"SELECT "+priceScheduleColumns+" FROM price_schedules WHERE id = ? FOR UPDATE", id,
+			), &s)
+			if err != nil {
+				return 0, nil, err
+			}
+			if s.Status != status {
+				return 0, nil, sql.ErrNoRows
+			}
+
+			change, err := transition(tx, &s)
+			if err != nil {
+				return 0, nil, err
+			}
+			return s.ProductID, change, tx.Commit()
+		}()
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Failed to process price schedule %d: %v", id, err)
+			}
+			continue
+		}
+		processed++
+		priceChanged(productID, change)
+	}
+	return processed
+}
+
+// CreatePriceSchedule 为商品创建计划价格
+func CreatePriceSchedule(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("create_price_schedule", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req models.PriceScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.EndsAt != nil {
+		if !req.EndsAt.After(req.StartsAt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+			return
+		}
+		if !req.EndsAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be in the future"})
+			return
+		}
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	baseCurrency, err := lockProductCurrency(tx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = baseCurrency
+	}
+	price, err := models.ParseMoney(req.Amount, currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !price.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be greater than zero"})
+		return
+	}
+
+	// 同一币种未结束的计划之间不能重叠，商品行已锁定，不会并发插入
+	var endsAt interface{}
+	overlap := "SELECT id FROM price_schedules WHERE product_id = ? AND currency = ? AND status IN (?, ?)" +
+		" AND (ends_at IS NULL OR ends_at > ?)"
+	args := []interface{}{productID, price.Currency, models.PriceSchedulePending, models.PriceScheduleActive, req.StartsAt}
+	if req.EndsAt != nil {
+		endsAt = *req.EndsAt
+		overlap += " AND starts_at < ?"
+		args = append(args, *req.EndsAt)
+	}
+	var conflictID int
+	err = tx.QueryRow(overlap+" ORDER BY starts_at LIMIT 1", args...).Scan(&conflictID)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":                   "Price schedule overlaps an existing schedule",
+			"conflicting_schedule_id": conflictID,
+		})
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO price_schedules (product_id, currency, amount, starts_at, ends_at, status, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, productID, price.Currency, price.Amount, req.StartsAt, endsAt, models.PriceSchedulePending, c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price schedule"})
+		return
+	}
+	scheduleID, _ := result.LastInsertId()
+
+	var schedule models.PriceSchedule
+	err = scanPriceSchedule(tx.QueryRow(
+		"SELECT "+priceScheduleColumns+" FROM price_schedules WHERE id = ?", scheduleID,
+	), &schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "price_schedule", scheduleID)
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// ListPriceSchedules 列出商品的价格计划，可按 status 过滤
+func ListPriceSchedules(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("list_price_schedules", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	query := "SELECT " + priceScheduleColumns + " FROM price_schedules WHERE product_id = ?"
+	args := []interface{}{productID}
+	if status := c.Query("status"); status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	schedules, err := queryPriceSchedules(query+" ORDER BY starts_at, id", args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+func queryPriceSchedules(query string, args ...interface{}) ([]models.PriceSchedule, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.PriceSchedule{}
+	for rows.Next() {
+		var s models.PriceSchedule
+		if err := scanPriceSchedule(rows, &s); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// CancelPriceSchedule 取消价格计划，已生效的计划立即结束并恢复生效前的价格
+func CancelPriceSchedule(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("cancel_price_schedule", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	scheduleID, err := strconv.Atoi(c.Param("scheduleId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var s models.PriceSchedule
+	err = scanPriceSchedule(tx.QueryRow(
+		"SELECT "+priceScheduleColumns+" FROM price_schedules WHERE id = ? AND product_id = ? FOR UPDATE",
+		scheduleID, productID,
+	), &s)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Price schedule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	var change *models.PriceChange
+	switch s.Status {
+	case models.PriceSchedulePending:
+		_, err = tx.Exec(
+			"UPDATE price_schedules SET status = ?, ended_at = ? WHERE id = ?",
+			models.PriceScheduleCancelled, time.Now(), s.ID,
+		)
+	case models.PriceScheduleActive:
+		change, err = finishPriceSchedule(tx, &s, models.PriceScheduleCancelled, c.GetInt("userID"), time.Now())
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "Price schedule has already ended", "status": s.Status})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel price schedule"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "price_schedule", scheduleID)
+	priceChanged(productID, change)
+	c.JSON(http.StatusOK, gin.H{"message": "Price schedule cancelled"})
+}
+
+// snapshotPrices 快照中各币种的价格（最小货币单位）
+func snapshotPrices(p models.ProductDetail) map[string]int64 {
+	prices := map[string]int64{}
+	if p.Price.Currency != "" {
+		prices[p.Price.Currency] = p.Price.Amount
+	}
+	for _, price := range p.Prices {
+		prices[price.Currency] = price.Amount
+	}
+	return prices
+}
+
+// GetProductPriceHistory 根据修订历史还原商品各币种价格的有效期。
+// 尚未生效的价格计划包含未公开的价格和创建者，只返回给已认证的调用方
+func GetProductPriceHistory(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("price_history", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	currency := ""
+	if value := c.Query("currency"); value != "" {
+		if currency, err = models.NormalizeCurrency(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	product, err := loadProductDetail(database.DB, productID, false)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if product.Status != models.ProductStatusPublished && !canViewUnpublished(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	type priceState struct {
+		at       time.Time
+		revision int
+		action   string
+		prices   map[string]int64
+	}
+	var states []priceState
+
+	rows, err := database.DB.Query(`
+		SELECT revision, action, snapshot, created_at
+		FROM product_revisions
+		WHERE product_id = ?
+		ORDER BY revision
+	`, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var state priceState
+		var snapshotJSON []byte
+		if err := rows.Scan(&state.revision, &state.action, &snapshotJSON, &state.at); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		var snapshot models.ProductDetail
+		if err := json.Unmarshal(snapshotJSON, &snapshot); err != nil {
+			log.Printf("Error decoding revision %d of product %d: %v", state.revision, productID, err)
+			continue
+		}
+		// 基线修订记录的是功能上线前的状态，从商品创建时开始有效
+		if state.action == models.RevisionActionBaseline {
+			state.at = product.CreatedAt
+		}
+		state.prices = snapshotPrices(snapshot)
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	// 从未修改过的商品只有当前价格
+	if len(states) == 0 {
+		states = append(states, priceState{
+			at:     product.CreatedAt,
+			action: models.RevisionActionBaseline,
+			prices: snapshotPrices(product),
+		})
+	}
+
+	history := []models.PriceHistoryEntry{}
+	open := map[string]int{}
+	for _, state := range states {
+		currencies := make([]string, 0, len(state.prices)+len(open))
+		for code := range state.prices {
+			currencies = append(currencies, code)
+		}
+		for code := range open {
+			if _, ok := state.prices[code]; !ok {
+				currencies = append(currencies, code)
+			}
+		}
+		sort.Strings(currencies)
+
+		for _, code := range currencies {
+			if currency != "" && code != currency {
+				continue
+			}
+			var price *models.Money
+			if amount, ok := state.prices[code]; ok {
+				price = &models.Money{Amount: amount, Currency: code}
+			}
+
+			i, seen := open[code]
+			if seen && sameMoney(history[i].Price, price) {
+				continue
+			}
+			if seen {
+				at := state.at
+				history[i].EffectiveTo = &at
+			}
+			history = append(history, models.PriceHistoryEntry{
+				Currency:      code,
+				Price:         price,
+				EffectiveFrom: state.at,
+				Revision:      state.revision,
+				Action:        state.action,
+			})
+			open[code] = len(history) - 1
+		}
+	}
+
+	response := gin.H{
+		"product_id": productID,
+		"history":    history,
+	}
+	if canViewUnpublished(c) {
+		query := "SELECT " + priceScheduleColumns + " FROM price_schedules WHERE product_id = ? AND status = ?"
+		args := []interface{}{productID, models.PriceSchedulePending}
+		if currency != "" {
+			query += " AND currency = ?"
+			args = append(args, currency)
+		}
+		scheduled, err := queryPriceSchedules(query+" ORDER BY starts_at, id", args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		response["scheduled"] = scheduled
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"product-service/promotions"
+	"product-service/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 报价允许的最大数量
+const maxQuoteQuantity = 1000000
+
+func loadPriceTiers(q querier, productID int) ([]models.PriceTier, error) {
+	rows, err := q.Query(`
+		SELECT id, customer_group, currency, min_quantity, amount, created_at
+		FROM product_price_tiers
+		WHERE product_id = ?
+		ORDER BY customer_group, currency, min_quantity
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tiers := []models.PriceTier{}
+	for rows.Next() {
+		var tier models.PriceTier
+		if err := rows.Scan(
+			&tier.ID, &tier.CustomerGroup, &tier.Price.Currency, &tier.MinQuantity, &tier.Price.Amount, &tier.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, rows.Err()
+}
+
+// normalizePriceTiers 校验阶梯价格，同一客户分组、币种和起订数量只能有一条
+func normalizePriceTiers(tiers []models.PriceTier) error {
+	seen := map[string]bool{}
+	for i := range tiers {
+		tier := &tiers[i]
+		if tier.CustomerGroup != "" {
+			group, err := utils.NormalizeCustomerGroup(tier.CustomerGroup)
+			if err != nil {
+				return err
+			}
+			tier.CustomerGroup = group
+		}
+		if !tier.Price.IsPositive() {
+			return errors.New("tier price must be greater than zero")
+		}
+
+		key := fmt.Sprintf("%s|%s|%d", tier.CustomerGroup, tier.Price.Currency, tier.MinQuantity)
+		if seen[key] {
+			return fmt.Errorf("duplicate tier for customer group %q, currency %s and min_quantity %d",
+				tier.CustomerGroup, tier.Price.Currency, tier.MinQuantity)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// ListPriceTiers 列出商品的全部阶梯价格
+func ListPriceTiers(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("list_price_tiers", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var exists bool
+	err = database.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM products WHERE id = ? AND deleted_at IS NULL)", productID,
+	).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	tiers, err := loadPriceTiers(database.DB, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"product_id": productID, "tiers": tiers})
+}
+
+// ReplacePriceTiers 整体替换商品的阶梯价格，传入空列表时删除全部阶梯价格
+func ReplacePriceTiers(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("replace_price_tiers", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req models.PriceTiersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizePriceTiers(req.Tiers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(tx, productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_price_tiers WHERE product_id = ?", productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update price tiers"})
+		return
+	}
+	for _, tier := range req.Tiers {
+		_, err := tx.Exec(`
+			INSERT INTO product_price_tiers (product_id, customer_group, currency, min_quantity, amount)
+			VALUES (?, ?, ?, ?, ?)
+		`, productID, tier.CustomerGroup, tier.Price.Currency, tier.MinQuantity, tier.Price.Amount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update price tiers"})
+			return
+		}
+	}
+
+	tiers, err := loadPriceTiers(tx, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 阶梯价格不在修订快照中，不记录修订，变更由审计日志记录
+	if err := commitPriceChange(c, tx, productID, ""); err != nil {
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	c.JSON(http.StatusOK, gin.H{"product_id": productID, "tiers": tiers})
+}
+
+// QuoteProductPrice 按数量和调用方的客户分组计算商品单价。
+// 适用的阶梯价格包括通用阶梯和调用方分组的阶梯，取其中与标价、促销价相比最低的单价；
+// 与请求币种不同的阶梯价格只有在其为商品基础币种时才按汇率换算后参与比较
+func QuoteProductPrice(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("quote", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	quantity, err := strconv.Atoi(c.DefaultQuery("quantity", "1"))
+	if err != nil || quantity < 1 || quantity > maxQuoteQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("quantity must be between 1 and %d", maxQuoteQuantity)})
+		return
+	}
+
+	product, err := loadProductDetail(database.DB, productID, false)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if product.Status != models.ProductStatusPublished && !canViewUnpublished(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	currency := product.Price.Currency
+	if value := c.Query("currency"); value != "" {
+		if currency, err = models.NormalizeCurrency(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	applyDisplayPrice(&product, currency)
+	if product.DisplayPrice == nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Product has no price in " + currency})
+		return
+	}
+
+	quote := models.PriceQuote{
+		ProductID:     productID,
+		Quantity:      quantity,
+		CustomerGroup: c.GetString("customerGroup"),
+		ListPrice:     *product.DisplayPrice,
+		UnitPrice:     *product.DisplayPrice,
+		ExchangeRate:  product.ExchangeRate,
+		QuotedAt:      time.Now(),
+	}
+
+	tiers, err := loadPriceTiers(database.DB, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	for _, tier := range tiers {
+		if tier.MinQuantity > quantity || (tier.CustomerGroup != "" && tier.CustomerGroup != quote.CustomerGroup) {
+			continue
+		}
+
+		price := tier.Price
+		var rate *models.AppliedExchangeRate
+		if price.Currency != currency {
+			if price.Currency != product.Price.Currency {
+				continue
+			}
+			converted, ok := exchangeRates.convert(price, currency)
+			if !ok {
+				continue
+			}
+			price = converted
+			rate, _ = exchangeRates.applied(tier.Price.Currency, currency)
+		}
+
+		if price.Amount < quote.UnitPrice.Amount {
+			tier := tier
+			quote.UnitPrice = price
+			quote.AppliedTier = &tier
+			quote.ExchangeRate = rate
+		}
+	}
+
+	// 促销价低于阶梯价格时按促销价计算，买赠促销另外按数量扣除赠送件数
+	item := promotions.Item{
+		ProductID: productID, CategoryID: product.CategoryID, SKU: product.SKU, Attributes: product.Attributes,
+	}
+	if promotion := promotionEngine.Apply(item, quote.ListPrice, quote.QuotedAt, exchangeRates.convert); promotion != nil {
+		if len(promotion.Applied) > 0 && promotion.DiscountedPrice.Amount < quote.UnitPrice.Amount {
+			quote.UnitPrice = promotion.DiscountedPrice
+			quote.AppliedTier = nil
+			quote.ExchangeRate = product.ExchangeRate
+			quote.Promotion = promotion
+		}
+		quote.AppliedOffer, quote.FreeQuantity = promotions.BestOffer(promotion.Offers, quantity)
+	}
+
+	paid := int64(quantity - quote.FreeQuantity)
+	if quote.UnitPrice.Amount > math.MaxInt64/paid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quote total is too large"})
+		return
+	}
+	quote.Total = models.Money{Amount: quote.UnitPrice.Amount * paid, Currency: currency}
+
+	c.JSON(http.StatusOK, quote)
+}
@@ -0,0 +1,407 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attributeEvent 属性变更后发送的事件
+type attributeEvent struct {
+	eventType string
+	attribute models.ProductAttribute
+}
+
+// lockProductForAttributes 锁定商品并返回其状态、分类和分类的属性定义，已写入响应时返回 false
+func lockProductForAttributes(c *gin.Context, tx *sql.Tx, productID int) (string, int, []models.AttributeDefinition, bool) {
+	status, _, err := lockProductStatus(tx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return "", 0, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return "", 0, nil, false
+	}
+
+	var categoryID int
+	if err := tx.QueryRow("SELECT category_id FROM products WHERE id = ?", productID).Scan(&categoryID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return "", 0, nil, false
+	}
+	definitions, err := loadAttributeDefinitions(tx, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return "", 0, nil, false
+	}
+	return status, categoryID, definitions, true
+}
+
+// respondMissingRequiredAttributes 已发布或定时发布的商品修改属性后仍须包含分类的必填属性，
+// 缺少时返回 409，已写入响应时返回 true
+func respondMissingRequiredAttributes(c *gin.Context, tx *sql.Tx, productID, categoryID int, status string) bool {
+	if status != models.ProductStatusPublished && status != models.ProductStatusScheduled {
+		return false
+	}
+	missing, err := missingRequiredAttributes(tx, productID, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return true
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":              "Required attributes cannot be removed from a " + status + " product",
+			"missing_attributes": missing,
+		})
+		return true
+	}
+	return false
+}
+
+// UpdateProductAttribute 修改商品属性，PUT /products/:id/attributes/:attrId
+func UpdateProductAttribute(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("update_attribute", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	attributeID, err := strconv.Atoi(c.Param("attrId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute ID"})
+		return
+	}
+
+	var attribute models.ProductAttribute
+	if err := c.ShouldBindJSON(&attribute); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	status, categoryID, definitions, ok := lockProductForAttributes(c, tx, productID)
+	if !ok {
+		return
+	}
+	definition, err := normalizeProductAttribute(definitions, &attribute)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM product_attributes WHERE id = ? AND product_id = ?)", attributeID, productID,
+	).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attribute not found"})
+		return
+	}
+	// 已定义的属性每个商品只有一个值
+	if definition != nil {
+		var existingID int
+		err := tx.QueryRow(
+			"SELECT id FROM product_attributes WHERE product_id = ? AND name = ? AND id <> ? LIMIT 1",
+			productID, attribute.Name, attributeID,
+		).Scan(&existingID)
+		if err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Attribute already exists", "attribute_id": existingID})
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	_, err = tx.Exec(
+		"UPDATE product_attributes SET name = ?, value = ?, number_value = ? WHERE id = ? AND product_id = ?",
+		attribute.Name, attribute.Value, attributeNumberValue(attribute.Value), attributeID, productID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update attribute"})
+		return
+	}
+	// 改名可能去掉必填属性
+	if respondMissingRequiredAttributes(c, tx, productID, categoryID, status) {
+		return
+	}
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionUpdateAttribute, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	attribute.ID = attributeID
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventAttributeUpdated, productID, attribute)
+	}
+	reindexProducts(productID)
+
+	c.JSON(http.StatusOK, attribute)
+}
+
+// DeleteProductAttribute 删除商品属性，DELETE /products/:id/attributes/:attrId
+func DeleteProductAttribute(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("delete_attribute", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	attributeID, err := strconv.Atoi(c.Param("attrId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute ID"})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	status, categoryID, _, ok := lockProductForAttributes(c, tx, productID)
+	if !ok {
+		return
+	}
+
+	attribute := models.ProductAttribute{ID: attributeID}
+	err = tx.QueryRow(
+		"SELECT name, value FROM product_attributes WHERE id = ? AND product_id = ?", attributeID, productID,
+	).Scan(&attribute.Name, &attribute.Value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attribute not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_attributes WHERE id = ? AND product_id = ?", attributeID, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attribute"})
+		return
+	}
+	if respondMissingRequiredAttributes(c, tx, productID, categoryID, status) {
+		return
+	}
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionRemoveAttribute, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventAttributeRemoved, productID, attribute)
+	}
+	reindexProducts(productID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attribute deleted"})
+}
+
+// ReplaceProductAttributes 整体替换商品的属性集合，PUT /products/:id/attributes。
+// 名称和值都相同的属性保持不变，名称相同的属性修改值并保留ID，其余新增或删除，每项变化发送对应的事件
+func ReplaceProductAttributes(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("replace_attributes", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req models.ProductAttributesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	status, categoryID, definitions, ok := lockProductForAttributes(c, tx, productID)
+	if !ok {
+		return
+	}
+
+	// 校验新的属性集合，已定义的属性只能出现一次
+	defined := map[string]bool{}
+	for i := range req.Attributes {
+		definition, err := normalizeProductAttribute(definitions, &req.Attributes[i])
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if definition != nil {
+			if defined[definition.Name] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Attribute " + definition.Name + " is specified more than once"})
+				return
+			}
+			defined[definition.Name] = true
+		}
+	}
+
+	existing, err := loadProductAttributes(tx, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 先匹配名称和值都相同的属性，再按名称匹配需要修改值的属性
+	matched := make([]bool, len(existing))
+	assigned := make([]int, len(req.Attributes))
+	for i := range assigned {
+		assigned[i] = -1
+	}
+	for _, sameValue := range []bool{true, false} {
+		for i, attr := range req.Attributes {
+			if assigned[i] >= 0 {
+				continue
+			}
+			for j, old := range existing {
+				if matched[j] || !strings.EqualFold(old.Name, attr.Name) || (sameValue && old.Value != attr.Value) {
+					continue
+				}
+				matched[j], assigned[i] = true, j
+				break
+			}
+		}
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	var events []attributeEvent
+	for j, old := range existing {
+		if matched[j] {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM product_attributes WHERE id = ? AND product_id = ?", old.ID, productID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace attributes"})
+			return
+		}
+		events = append(events, attributeEvent{models.EventAttributeRemoved, old})
+	}
+	for i, attr := range req.Attributes {
+		if assigned[i] >= 0 {
+			old := existing[assigned[i]]
+			attr.ID = old.ID
+			req.Attributes[i] = attr
+			if old.Name == attr.Name && old.Value == attr.Value {
+				continue
+			}
+			_, err := tx.Exec(
+				"UPDATE product_attributes SET name = ?, value = ?, number_value = ? WHERE id = ? AND product_id = ?",
+				attr.Name, attr.Value, attributeNumberValue(attr.Value), attr.ID, productID,
+			)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace attributes"})
+				return
+			}
+			events = append(events, attributeEvent{models.EventAttributeUpdated, attr})
+			continue
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO product_attributes (product_id, name, value, number_value)
+			VALUES (?, ?, ?, ?)
+		`, productID, attr.Name, attr.Value, attributeNumberValue(attr.Value))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace attributes"})
+			return
+		}
+		id, _ := result.LastInsertId()
+		attr.ID = int(id)
+		req.Attributes[i] = attr
+		events = append(events, attributeEvent{models.EventAttributeAdded, attr})
+	}
+	if respondMissingRequiredAttributes(c, tx, productID, categoryID, status) {
+		return
+	}
+
+	// 没有变化时不记录修订
+	if len(events) > 0 {
+		if _, err := recordRevision(tx, productID, models.RevisionActionReplaceAttributes, c.GetInt("userID")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+			return
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	if len(events) > 0 {
+		if rabbitMQ != nil {
+			for _, e := range events {
+				sendProductEvent(e.eventType, productID, e.attribute)
+			}
+		}
+		reindexProducts(productID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attributes": req.Attributes})
+}
@@ -45,7 +45,8 @@ func sendProductEvent(eventType string, productID int, data interface{}) {
 
 	// 根据事件类型设置数据
 	switch eventType {
-	case models.EventProductCreated, models.EventProductUpdated:
+	case models.EventProductCreated, models.EventProductUpdated, models.EventProductRestored,
+		models.EventProductPublished, models.EventProductArchived:
 		if product, ok := data.(models.Product); ok {
 			event.ProductData = product
 		}
@@ -53,14 +54,26 @@ func sendProductEvent(eventType string, productID int, data interface{}) {
 		if categoryID, ok := data.(int); ok {
 			event.CategoryID = categoryID
 		}
-	case models.EventImageAdded:
+	case models.EventImageAdded, models.EventImageUpdated, models.EventImageRemoved:
 		if image, ok := data.(models.ProductImage); ok {
 			event.ImageData = image
 		}
-	case models.EventAttributeAdded:
+	case models.EventAttributeAdded, models.EventAttributeUpdated, models.EventAttributeRemoved:
 		if attr, ok := data.(models.ProductAttribute); ok {
 			event.Attribute = attr
 		}
+	case models.EventProductsImported:
+		if ids, ok := data.([]int); ok {
+			event.ProductIDs = ids
+		}
+	case models.EventPriceChanged:
+		if change, ok := data.(models.PriceChange); ok {
+			event.PriceChange = &change
+		}
+	case models.EventPromotionStarted, models.EventPromotionEnded:
+		if promotion, ok := data.(models.Promotion); ok {
+			event.Promotion = &promotion
+		}
 	}
 
 	// 发布事件
@@ -75,6 +88,28 @@ func sendProductEvent(eventType string, productID int, data interface{}) {
 	}
 }
 
+// sendBroadcastEvent 通知其他副本刷新进程内缓存，消息不进入商品事件队列
+func sendBroadcastEvent(eventType string) {
+	if rabbitMQ == nil {
+		return
+	}
+
+	event := models.ProductEvent{
+		EventID:   generateEventID(),
+		EventType: eventType,
+		Timestamp: time.Now(),
+	}
+	eventBody, err := event.ToJSON()
+	if err != nil {
+		log.Printf("Failed to marshal event: %v", err)
+		return
+	}
+
+	if err := rabbitMQ.PublishBroadcast(eventBody); err != nil {
+		log.Printf("Failed to publish %s event: %v", eventType, err)
+	}
+}
+
 func CreateCategory(c *gin.Context) {
 	defer func() {
 		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
@@ -85,10 +120,16 @@ func CreateCategory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	prefix, err := normalizeCategorySKUPrefix(category.SKUPrefix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category.SKUPrefix = prefix
 
 	result, err := database.DB.Exec(
-		"INSERT INTO categories (name, description) VALUES (?, ?)",
-		category.Name, category.Description,
+		"INSERT INTO categories (name, description, sku_prefix) VALUES (?, ?, ?)",
+		category.Name, category.Description, category.SKUPrefix,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
@@ -96,7 +137,7 @@ func CreateCategory(c *gin.Context) {
 	}
 
 	categoryID, _ := result.LastInsertId()
-	c.JSON(http.StatusCreated, gin.H{"id": categoryID})
+	middlewares.SetAuditEntity(c, "category", categoryID)
 
 	if rabbitMQ != nil {
 		sendProductEvent(models.EventCategoryCreated, 0, int(categoryID))
@@ -115,6 +156,27 @@ func CreateProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateProductPrice(product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateSKU(&product.SKU); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateGTIN(&product.GTIN); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateProductMeasures(&product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 新建商品默认为草稿，不对公开接口可见
+	if err := normalizeNewProductStatus(&product, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 验证分类是否存在
 	var exists bool
@@ -126,6 +188,21 @@ func CreateProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
 		return
 	}
+	// 新建的商品还没有属性，分类有必填属性时只能先创建为草稿
+	if product.Status != models.ProductStatusDraft {
+		missing, err := missingRequiredAttributes(database.DB, 0, product.CategoryID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if len(missing) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":              "Category has required attributes; create the product as a draft and add them before publishing",
+				"missing_attributes": missing,
+			})
+			return
+		}
+	}
 
 	// 开始事务
 	tx, err := database.DB.Begin()
@@ -134,17 +211,41 @@ func CreateProduct(c *gin.Context) {
 		return
 	}
 
+	// 未指定 SKU 时按模板生成，指定时检查是否已被其他商品使用
+	if product.SKU == "" && skuAutoGenerate {
+		sku, err := generateSKU(tx, product.CategoryID)
+		if err != nil {
+			_ = tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate SKU"})
+			return
+		}
+		product.SKU = sku
+	} else if respondSKUConflict(c, tx, product.SKU, 0) {
+		_ = tx.Rollback()
+		return
+	}
+	if respondGTINConflict(c, tx, product.GTIN, 0) {
+		_ = tx.Rollback()
+		return
+	}
+
 	// 插入产品
 	result, err := tx.Exec(
 		`INSERT INTO products 
-		(name, description, price, stock, category_id, sku, image_url) 
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		product.Name, product.Description, product.Price, product.Stock,
-		product.CategoryID, product.SKU, product.ImageURL,
+		(name, description, price, price_amount, currency, stock, category_id, sku, gtin, image_url,
+		 weight, weight_unit, length, width, height, dimension_unit, status, publish_at, published_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		product.Name, product.Description, product.Price.Decimal(), product.Price.Amount, product.Price.Currency, product.Stock,
+		product.CategoryID, product.SKU, product.GTIN, product.ImageURL,
+		product.Weight.Value, product.Weight.Unit, product.Dimensions.Length, product.Dimensions.Width, product.Dimensions.Height, product.Dimensions.Unit, product.Status, product.PublishAt, product.PublishedAt,
 	)
 	if err != nil {
-		err := tx.Rollback()
-		if err != nil {
+		if err := tx.Rollback(); err != nil {
+			return
+		}
+		// 并发创建相同 SKU 或条码时由唯一索引拒绝
+		if isDuplicateKeyError(err) {
+			respondUniqueKeyConflict(c, product.SKU, product.GTIN, 0)
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
@@ -152,14 +253,30 @@ func CreateProduct(c *gin.Context) {
 	}
 
 	productID, _ := result.LastInsertId()
-	c.JSON(http.StatusCreated, gin.H{"id": productID})
+	middlewares.SetAuditEntity(c, "product", productID)
+
+	if _, err := recordRevision(tx, int(productID), models.RevisionActionCreate, c.GetInt("userID")); err != nil {
+		_ = tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
 
 	if rabbitMQ != nil {
 		product.ID = int(productID)
 		sendProductEvent(models.EventProductCreated, int(productID), product)
+		if product.Status == models.ProductStatusPublished {
+			sendProductEvent(models.EventProductPublished, int(productID), product)
+		}
 	}
+	reindexProducts(int(productID))
 
-	c.JSON(http.StatusCreated, gin.H{"id": productID})
+	c.JSON(http.StatusCreated, gin.H{"id": productID, "status": product.Status, "sku": product.SKU})
 }
 
 func GetProduct(c *gin.Context) {
@@ -173,20 +290,28 @@ func GetProduct(c *gin.Context) {
 		return
 	}
 
-	// 查询产品基本信息
-	var product models.ProductDetail
-	err = database.DB.QueryRow(`
-		SELECT p.id, p.name, p.description, p.price, p.stock, p.category_id, 
-		       p.sku, p.image_url, p.created_at, p.updated_at, c.name AS category_name
-		FROM products p
-		JOIN categories c ON p.category_id = c.id
-		WHERE p.id = ? AND p.deleted_at IS NULL
-	`, productID).Scan(
-		&product.ID, &product.Name, &product.Description, &product.Price,
-		&product.Stock, &product.CategoryID, &product.SKU, &product.ImageURL,
-		&product.CreatedAt, &product.UpdatedAt, &product.CategoryName,
-	)
+	renderProductDetail(c, productID)
+}
 
+// renderProductDetail 输出商品详情，指定 currency 时附带该币种的展示价格，
+// 指定 weight_unit、dimension_unit 时重量和尺寸换算为该单位
+func renderProductDetail(c *gin.Context, productID int) {
+	var err error
+	currency := ""
+	if value := c.Query("currency"); value != "" {
+		if currency, err = models.NormalizeCurrency(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	weightUnit, dimensionUnit, err := normalizeMeasureUnits(c.Query("weight_unit"), c.Query("dimension_unit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 查询产品及其属性、图片
+	product, err := loadProductDetail(database.DB, productID, false)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
@@ -195,107 +320,111 @@ func GetProduct(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
-
-	// 查询产品属性
-	rows, err := database.DB.Query(`
-		SELECT id, name, value
-		FROM product_attributes
-		WHERE product_id = ?
-	`, productID)
-	if err != nil {
-		log.Printf("Error fetching attributes: %v", err)
-	} else {
-		defer func(rows *sql.Rows) {
-			err := rows.Close()
-			if err != nil {
-
-			}
-		}(rows)
-		for rows.Next() {
-			var attr models.ProductAttribute
-			if err := rows.Scan(&attr.ID, &attr.Name, &attr.Value); err != nil {
-				log.Printf("Error scanning attribute: %v", err)
-				continue
-			}
-			product.Attributes = append(product.Attributes, attr)
-		}
+	// 未发布的商品只对已认证的调用方可见
+	if product.Status != models.ProductStatusPublished && !canViewUnpublished(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
 	}
-
-	// 查询产品图片
-	imgRows, err := database.DB.Query(`
-		SELECT id, image_url, is_primary
-		FROM product_images
-		WHERE product_id = ?
-	`, productID)
-	if err != nil {
-		log.Printf("Error fetching images: %v", err)
-	} else {
-		defer func(imgRows *sql.Rows) {
-			err := imgRows.Close()
-			if err != nil {
-
-			}
-		}(imgRows)
-		for imgRows.Next() {
-			var img models.ProductImage
-			if err := imgRows.Scan(&img.ID, &img.ImageURL, &img.IsPrimary); err != nil {
-				log.Printf("Error scanning image: %v", err)
-				continue
-			}
-			product.Images = append(product.Images, img)
-		}
+	if currency != "" {
+		applyDisplayPrice(&product, currency)
 	}
+	applyPromotion(&product, product.Attributes, time.Now())
+	applyMeasureUnits(&product, weightUnit, dimensionUnit)
 
 	c.JSON(http.StatusOK, product)
 }
 
 func ListProducts(c *gin.Context) {
+	// 传入 ids 时按ID批量获取
+	if value, ok := c.GetQuery("ids"); ok {
+		ids, err := parseProductIDList(value)
+		if err != nil {
+			middlewares.RecordProductOperation("batch_get", false)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respondProductsBatch(c, ids)
+		return
+	}
+
 	defer func() {
 		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
 		middlewares.RecordProductOperation("list", status)
 	}()
-	var filter models.ProductFilter
-	if err := c.ShouldBindQuery(&filter); err != nil {
+	filter, err := bindProductFilter(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// 默认只列出已发布的商品，查询其他状态需要管理员权限
+	if filter.Status == "" {
+		filter.Status = models.ProductStatusPublished
+	}
+	if filter.Status != models.ProductStatusPublished && !canViewUnpublished(c) {
+		if _, ok := c.Get("userID"); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication is required to list unpublished products"})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin privileges required to list unpublished products"})
+		return
+	}
 
-	pagination := utils.ParsePagination(c)
-
-	// 构建查询条件
-	query := `
-		SELECT p.id, p.name, p.description, p.price, p.stock, p.category_id, 
-		       p.sku, p.image_url, p.created_at, p.updated_at, c.name AS category_name
-		FROM products p
-		JOIN categories c ON p.category_id = c.id
-		WHERE p.deleted_at IS NULL
-	`
-	var args []interface{}
-	where := ""
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if filter.CategoryID > 0 {
-		where += " AND p.category_id = ?"
-		args = append(args, filter.CategoryID)
+	includes, err := parseProductIncludes(c.Query("include"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if filter.MinPrice > 0 {
-		where += " AND p.price >= ?"
-		args = append(args, filter.MinPrice)
+	fields, err := parseProductFields(c.Query("fields"), &includes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if filter.MaxPrice > 0 {
-		where += " AND p.price <= ?"
-		args = append(args, filter.MaxPrice)
+
+	// 构建查询条件
+	query, err := buildProductFilter(filter)
+	if err != nil {
+		log.Printf("Product search failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
 	}
-	if filter.Search != "" {
-		where += " AND (p.name LIKE ? OR p.description LIKE ? OR c.name LIKE ?)"
-		searchTerm := "%" + filter.Search + "%"
-		args = append(args, searchTerm, searchTerm, searchTerm)
+	where, args := query.where, query.args
+
+	sorting, err := parseProductSort(c.Query("sort"), query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	// 分页参数
-	args = append(args, pagination.PageSize, (pagination.Page-1)*pagination.PageSize)
+	// 传入游标时从游标位置继续读取，否则按页码偏移
+	pageWhere := where
+	pageArgs := append([]interface{}{}, args...)
+	offset := (pagination.Page - 1) * pagination.PageSize
+	if pagination.Cursor != "" {
+		values, err := sorting.decodeCursor(pagination.Cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		condition, conditionArgs := sorting.keysetCondition(values)
+		pageWhere += condition
+		pageArgs = append(pageArgs, conditionArgs...)
+		offset = 0
+	}
+	// 多取一条用于判断是否还有下一页
+	pageArgs = append(pageArgs, pagination.PageSize+1, offset)
 
 	// 执行查询
-	rows, err := database.DB.Query(query+where+" LIMIT ? OFFSET ?", args...)
+	rows, err := database.DB.Query(
+		"SELECT "+productDetailColumns+", "+query.priceExpr()+" AS list_price, "+productListedPriceExpr(query.currency)+" AS listed_price"+
+			productListFrom+pageWhere+sorting.orderBy()+" LIMIT ? OFFSET ?",
+		pageArgs...,
+	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
@@ -310,20 +439,43 @@ func ListProducts(c *gin.Context) {
 	var products []models.ProductDetail
 	for rows.Next() {
 		var p models.ProductDetail
-		if err := rows.Scan(
-			&p.ID, &p.Name, &p.Description, &p.Price, &p.Stock, &p.CategoryID,
-			&p.SKU, &p.ImageURL, &p.CreatedAt, &p.UpdatedAt, &p.CategoryName,
-		); err != nil {
+		var listPrice, listedPrice int64
+		if err := rows.Scan(append(productDetailDest(&p), &listPrice, &listedPrice)...); err != nil {
 			log.Printf("Error scanning product: %v", err)
 			continue
 		}
+		// 商品在查询币种下有价格时附带展示价格，换算得到的价格同时附带所用汇率。
+		// 展示价格与过滤、排序使用同一个 SQL 表达式的结果，保证游标一致
+		if listPrice >= 0 {
+			p.DisplayPrice = &models.Money{Amount: listPrice, Currency: query.currency}
+			if listedPrice < 0 {
+				p.ExchangeRate, _ = exchangeRates.applied(p.Price.Currency, query.currency)
+			}
+		}
+		applyMeasureUnits(&p, filter.WeightUnit, filter.DimensionUnit)
 		products = append(products, p)
 	}
 
+	nextCursor := ""
+	if len(products) > pagination.PageSize {
+		products = products[:pagination.PageSize]
+		nextCursor = sorting.encodeCursor(&products[len(products)-1])
+	}
+
+	// 关联数据按整页批量加载
+	if err := loadProductRelations(database.DB, products, includes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	// 促销按展示价格计算，过滤和排序仍使用促销前的价格
+	if err := applyPromotions(database.DB, products, includes.attributes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
 	// 获取总数
 	var total int
-	countQuery := "SELECT COUNT(*) FROM products p WHERE p.deleted_at IS NULL" + where
-	err = database.DB.QueryRow(countQuery, args[:len(args)-2]...).Scan(&total)
+	err = database.DB.QueryRow("SELECT COUNT(*)"+productListFrom+where, args...).Scan(&total)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
 		return
@@ -332,14 +484,29 @@ func ListProducts(c *gin.Context) {
 	totalPages := utils.CalculateTotalPages(total, pagination.PageSize)
 
 	response := models.ProductResponse{
-		Products:  products,
-		Total:     total,
-		Page:      pagination.Page,
-		PageSize:  pagination.PageSize,
-		TotalPage: totalPages,
+		Products:   products,
+		Total:      total,
+		Page:       pagination.Page,
+		PageSize:   pagination.PageSize,
+		TotalPage:  totalPages,
+		NextCursor: nextCursor,
+
+		SearchTruncated: query.searchTruncated,
+		IgnoredTerms:    query.ignoredTerms,
 	}
 
-	c.JSON(http.StatusOK, response)
+	// 默认返回分面统计，facets=false 时跳过
+	if c.DefaultQuery("facets", "true") != "false" {
+		facets, err := loadProductFacets(filter, query.rankedIDs)
+		if err != nil {
+			log.Printf("Failed to load product facets: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load facets"})
+			return
+		}
+		response.Facets = facets
+	}
+
+	renderProducts(c, response, products, fields)
 }
 
 func UpdateProduct(c *gin.Context) {
@@ -358,6 +525,18 @@ func UpdateProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateProductPrice(product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateGTIN(&product.GTIN); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateProductMeasures(&product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 开始事务
 	tx, err := database.DB.Begin()
@@ -365,25 +544,87 @@ func UpdateProduct(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
 		return
 	}
+	defer tx.Rollback()
+
+	status, publishAt, err := lockProductStatus(tx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	// 状态只能通过 PUT /products/:id/status 按状态机修改
+	if product.Status != "" && product.Status != status {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use PUT /products/:id/status to change product status"})
+		return
+	}
+	product.Status, product.PublishAt = status, publishAt
+
+	var currentSKU string
+	var currentCategoryID int
+	err = tx.QueryRow("SELECT sku, category_id FROM products WHERE id = ?", productID).Scan(&currentSKU, &currentCategoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if err := validateChangedSKU(&product.SKU, currentSKU); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 已发布或定时发布的商品移动到其他分类时须已设置新分类要求的全部属性
+	if product.CategoryID != currentCategoryID && (status == models.ProductStatusPublished || status == models.ProductStatusScheduled) {
+		missing, err := missingRequiredAttributes(tx, productID, product.CategoryID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if len(missing) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Missing required attributes for the new category", "missing_attributes": missing})
+			return
+		}
+	}
+	if respondSKUConflict(c, tx, product.SKU, productID) || respondGTINConflict(c, tx, product.GTIN, productID) {
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
 
 	// 更新产品
 	_, err = tx.Exec(`
 		UPDATE products 
-		SET name = ?, description = ?, price = ?, stock = ?, 
-		    category_id = ?, sku = ?, image_url = ?, updated_at = NOW()
+		SET name = ?, description = ?, price = ?, price_amount = ?, currency = ?, stock = ?, 
+		    category_id = ?, sku = ?, gtin = ?, image_url = ?,
+		    weight = ?, weight_unit = ?, length = ?, width = ?, height = ?, dimension_unit = ?, updated_at = NOW()
 		WHERE id = ?
 	`,
-		product.Name, product.Description, product.Price, product.Stock,
-		product.CategoryID, product.SKU, product.ImageURL, productID)
+		product.Name, product.Description, product.Price.Decimal(), product.Price.Amount, product.Price.Currency, product.Stock,
+		product.CategoryID, product.SKU, product.GTIN, product.ImageURL,
+		product.Weight.Value, product.Weight.Unit, product.Dimensions.Length, product.Dimensions.Width, product.Dimensions.Height, product.Dimensions.Unit,
+		productID)
 
 	if err != nil {
-		err := tx.Rollback()
-		if err != nil {
+		if isDuplicateKeyError(err) {
+			_ = tx.Rollback()
+			respondUniqueKeyConflict(c, product.SKU, product.GTIN, productID)
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
 		return
 	}
+	if err := dropBaseCurrencyPrice(tx, productID, product.Price.Currency); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
+		return
+	}
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionUpdate, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
 
 	// 提交事务
 	if err := tx.Commit(); err != nil {
@@ -391,12 +632,11 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Product updated"})
-
 	if rabbitMQ != nil {
 		product.ID = productID
 		sendProductEvent(models.EventProductUpdated, productID, product)
 	}
+	reindexProducts(productID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Product updated"})
 }
@@ -412,8 +652,30 @@ func DeleteProduct(c *gin.Context) {
 		return
 	}
 
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(tx, productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
 	// 软删除
-	_, err = database.DB.Exec(`
+	_, err = tx.Exec(`
 		UPDATE products 
 		SET deleted_at = NOW() 
 		WHERE id = ?
@@ -424,11 +686,21 @@ func DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
+	if _, err := recordRevision(tx, productID, models.RevisionActionDelete, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
 
 	if rabbitMQ != nil {
 		sendProductEvent(models.EventProductDeleted, productID, nil)
 	}
+	reindexProducts(productID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
 }
@@ -450,19 +722,27 @@ func AddProductImage(c *gin.Context) {
 		return
 	}
 
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
 	// 验证产品是否存在
-	var exists bool
-	err = database.DB.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM products WHERE id = ? AND deleted_at IS NULL)",
-		productID,
-	).Scan(&exists)
-	if err != nil || !exists {
+	if err := lockProduct(tx, productID); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
 
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
 	// 插入图片
-	result, err := database.DB.Exec(`
+	result, err := tx.Exec(`
 		INSERT INTO product_images (product_id, image_url, is_primary)
 		VALUES (?, ?, ?)
 	`, productID, image.ImageURL, image.IsPrimary)
@@ -473,7 +753,17 @@ func AddProductImage(c *gin.Context) {
 	}
 
 	imageID, _ := result.LastInsertId()
-	c.JSON(http.StatusCreated, gin.H{"id": imageID})
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionAddImage, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
 
 	if rabbitMQ != nil {
 		image.ID = int(imageID)
@@ -500,22 +790,62 @@ func AddProductAttribute(c *gin.Context) {
 		return
 	}
 
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
 	// 验证产品是否存在
-	var exists bool
-	err = database.DB.QueryRow(
-		"SELECT EXISTS(SELECT 1 FROM products WHERE id = ? AND deleted_at IS NULL)",
-		productID,
-	).Scan(&exists)
-	if err != nil || !exists {
+	if err := lockProduct(tx, productID); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
 
+	// 按分类的属性定义统一属性名并校验属性值
+	var categoryID int
+	if err := tx.QueryRow("SELECT category_id FROM products WHERE id = ?", productID).Scan(&categoryID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	definitions, err := loadAttributeDefinitions(tx, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	definition, err := normalizeProductAttribute(definitions, &attribute)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 已定义的属性每个商品只有一个值
+	if definition != nil {
+		var existingID int
+		err := tx.QueryRow(
+			"SELECT id FROM product_attributes WHERE product_id = ? AND name = ? LIMIT 1", productID, attribute.Name,
+		).Scan(&existingID)
+		if err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Attribute already exists", "attribute_id": existingID})
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
 	// 插入属性
-	result, err := database.DB.Exec(`
-		INSERT INTO product_attributes (product_id, name, value)
-		VALUES (?, ?, ?)
-	`, productID, attribute.Name, attribute.Value)
+	result, err := tx.Exec(`
+		INSERT INTO product_attributes (product_id, name, value, number_value)
+		VALUES (?, ?, ?, ?)
+	`, productID, attribute.Name, attribute.Value, attributeNumberValue(attribute.Value))
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add attribute"})
@@ -523,12 +853,23 @@ func AddProductAttribute(c *gin.Context) {
 	}
 
 	attrID, _ := result.LastInsertId()
-	c.JSON(http.StatusCreated, gin.H{"id": attrID})
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionAddAttribute, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
 
 	if rabbitMQ != nil {
 		attribute.ID = int(attrID)
 		sendProductEvent(models.EventAttributeAdded, productID, attribute)
 	}
+	reindexProducts(productID)
 
 	c.JSON(http.StatusCreated, gin.H{"id": attrID})
 }
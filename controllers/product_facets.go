@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"product-service/config"
+	"product-service/database"
+	"product-service/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 每个属性最多返回的取值数量
+const maxFacetValues = 50
+
+// priceFacetBounds 价格分面的区间边界
+var priceFacetBounds = []float64{0, 50, 100, 200, 500, 1000}
+
+// InitProductFacets 加载分面配置
+func InitProductFacets(cfg *config.Config) {
+	if bounds := parsePriceBuckets(cfg.PriceFacetBuckets); len(bounds) > 0 {
+		priceFacetBounds = bounds
+	}
+}
+
+// parsePriceBuckets 解析递增的价格边界，格式错误时返回 nil
+func parsePriceBuckets(value string) []float64 {
+	var bounds []float64
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(part, 64)
+		if err != nil || bound < 0 || (len(bounds) > 0 && bound <= bounds[len(bounds)-1]) {
+			log.Printf("Ignoring invalid PRICE_FACET_BUCKETS %q", value)
+			return nil
+		}
+		bounds = append(bounds, bound)
+	}
+	return bounds
+}
+
+// loadProductFacets 统计当前过滤条件下的分面。
+// 每个分面统计时忽略自身的过滤条件，使已选中的维度仍能展示其他可选值
+func loadProductFacets(filter models.ProductFilter, rankedIDs []int) (*models.ProductFacets, error) {
+	facets := &models.ProductFacets{
+		Attributes: []models.AttributeFacet{},
+		Categories: []models.CategoryFacet{},
+		Prices:     []models.PriceBucketFacet{},
+	}
+
+	var err error
+	if facets.Attributes, err = loadAttributeFacets(filter, rankedIDs); err != nil {
+		return nil, err
+	}
+	if facets.Categories, err = loadCategoryFacets(filter, rankedIDs); err != nil {
+		return nil, err
+	}
+	if facets.Prices, err = loadPriceFacets(filter, rankedIDs); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+func loadAttributeFacets(filter models.ProductFilter, rankedIDs []int) ([]models.AttributeFacet, error) {
+	const from = `
+		FROM products p
+		JOIN categories c ON p.category_id = c.id
+		JOIN product_attributes pa ON pa.product_id = p.id
+		WHERE p.deleted_at IS NULL`
+	const columns = "SELECT pa.name, pa.value, COUNT(DISTINCT p.id) AS cnt"
+	const groupBy = " GROUP BY pa.name, pa.value ORDER BY pa.name, cnt DESC, pa.value"
+
+	var selectedNames []string
+	for name := range filter.Attributes {
+		selectedNames = append(selectedNames, name)
+	}
+	sort.Strings(selectedNames)
+
+	counts := map[string][]models.FacetValue{}
+	collect := func(query string, args []interface{}) error {
+		rows, err := database.DB.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			var value models.FacetValue
+			if err := rows.Scan(&name, &value.Value, &value.Count); err != nil {
+				return err
+			}
+			counts[name] = append(counts[name], value)
+		}
+		return rows.Err()
+	}
+
+	// 未被选中的属性使用完整的过滤条件统计
+	where, args := productFilterClauses(filter, rankedIDs)
+	if len(selectedNames) > 0 {
+		where += " AND pa.name NOT IN " + inPlaceholders(len(selectedNames))
+		for _, name := range selectedNames {
+			args = append(args, name)
+		}
+	}
+	if err := collect(columns+from+where+groupBy, args); err != nil {
+		return nil, err
+	}
+
+	// 已选中的属性去掉自身的过滤条件后统计
+	for _, name := range selectedNames {
+		without := withoutAttribute(filter, name)
+		where, args := productFilterClauses(without, rankedIDs)
+		where += " AND pa.name = ?"
+		args = append(args, name)
+		if err := collect(columns+from+where+groupBy, args); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]models.AttributeFacet, 0, len(names))
+	for _, name := range names {
+		values := counts[name]
+		if len(values) > maxFacetValues {
+			values = values[:maxFacetValues]
+		}
+		for i := range values {
+			values[i].Selected = containsString(filter.Attributes[name], values[i].Value)
+		}
+		result = append(result, models.AttributeFacet{Name: name, Values: values})
+	}
+	return result, nil
+}
+
+func loadCategoryFacets(filter models.ProductFilter, rankedIDs []int) ([]models.CategoryFacet, error) {
+	without := filter
+	without.CategoryID = 0
+	where, args := productFilterClauses(without, rankedIDs)
+
+	rows, err := database.DB.Query(
+		"SELECT c.id, c.name, COUNT(*) AS cnt"+productListFrom+where+" GROUP BY c.id, c.name ORDER BY cnt DESC, c.id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.CategoryFacet{}
+	for rows.Next() {
+		var facet models.CategoryFacet
+		if err := rows.Scan(&facet.CategoryID, &facet.Name, &facet.Count); err != nil {
+			return nil, err
+		}
+		facet.Selected = facet.CategoryID == filter.CategoryID
+		result = append(result, facet)
+	}
+	return result, rows.Err()
+}
+
+func loadPriceFacets(filter models.ProductFilter, rankedIDs []int) ([]models.PriceBucketFacet, error) {
+	if len(priceFacetBounds) == 0 {
+		return []models.PriceBucketFacet{}, nil
+	}
+
+	without := filter
+	without.MinPrice = ""
+	without.MaxPrice = ""
+	where, args := productFilterClauses(without, rankedIDs)
+
+	// 区间边界按查询币种换算为最小货币单位，每个区间一列，一次查询得到全部区间的数量
+	currency := filterCurrency(filter)
+	price := productPriceExpr(currency)
+	var columns []string
+	var bucketArgs []interface{}
+	buckets := make([]models.PriceBucketFacet, len(priceFacetBounds))
+	for i, bound := range priceFacetBounds {
+		buckets[i].Min = majorToMoney(bound, currency)
+		if i+1 < len(priceFacetBounds) {
+			upper := majorToMoney(priceFacetBounds[i+1], currency)
+			buckets[i].Max = &upper
+			columns = append(columns, "COALESCE(SUM("+price+" >= ? AND "+price+" < ?), 0)")
+			bucketArgs = append(bucketArgs, buckets[i].Min.Amount, upper.Amount)
+		} else {
+			columns = append(columns, "COALESCE(SUM("+price+" >= ?), 0)")
+			bucketArgs = append(bucketArgs, buckets[i].Min.Amount)
+		}
+	}
+
+	dest := make([]interface{}, len(buckets))
+	for i := range buckets {
+		dest[i] = &buckets[i].Count
+	}
+	err := database.DB.QueryRow(
+		"SELECT "+strings.Join(columns, ", ")+productListFrom+where,
+		append(bucketArgs, args...)...,
+	).Scan(dest...)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// majorToMoney 将以主单位表示的区间边界换算为指定币种的金额
+func majorToMoney(major float64, currency string) models.Money {
+	exp, _ := models.CurrencyExponent(currency)
+	return models.Money{Amount: int64(math.Round(major * math.Pow10(exp))), Currency: currency}
+}
+
+// withoutAttribute 返回去掉指定属性过滤后的条件副本
+func withoutAttribute(filter models.ProductFilter, name string) models.ProductFilter {
+	attributes := make(map[string][]string, len(filter.Attributes))
+	for k, v := range filter.Attributes {
+		if k != name {
+			attributes[k] = v
+		}
+	}
+	filter.Attributes = attributes
+	return filter
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseProductImageIDs 解析路径中的商品ID和图片ID，已写入响应时返回 false
+func parseProductImageIDs(c *gin.Context) (int, int, bool) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return 0, 0, false
+	}
+	imageID, err := strconv.Atoi(c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return 0, 0, false
+	}
+	return productID, imageID, true
+}
+
+// UpdateProductImage 修改商品图片，PUT /products/:id/images/:imageId
+func UpdateProductImage(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("update_image", status)
+	}()
+	productID, imageID, ok := parseProductImageIDs(c)
+	if !ok {
+		return
+	}
+
+	var image models.ProductImage
+	if err := c.ShouldBindJSON(&image); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// 验证产品是否存在
+	if err := lockProduct(tx, productID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	var exists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM product_images WHERE id = ? AND product_id = ?)", imageID, productID,
+	).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	_, err = tx.Exec(
+		"UPDATE product_images SET image_url = ?, is_primary = ? WHERE id = ? AND product_id = ?",
+		image.ImageURL, image.IsPrimary, imageID, productID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update image"})
+		return
+	}
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionUpdateImage, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	image.ID = imageID
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventImageUpdated, productID, image)
+	}
+
+	c.JSON(http.StatusOK, image)
+}
+
+// DeleteProductImage 删除商品图片，DELETE /products/:id/images/:imageId
+func DeleteProductImage(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("delete_image", status)
+	}()
+	productID, imageID, ok := parseProductImageIDs(c)
+	if !ok {
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// 验证产品是否存在
+	if err := lockProduct(tx, productID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	image := models.ProductImage{ID: imageID}
+	err = tx.QueryRow(
+		"SELECT image_url, is_primary FROM product_images WHERE id = ? AND product_id = ?", imageID, productID,
+	).Scan(&image.ImageURL, &image.IsPrimary)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_images WHERE id = ? AND product_id = ?", imageID, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image"})
+		return
+	}
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionRemoveImage, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventImageRemoved, productID, image)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image deleted"})
+}
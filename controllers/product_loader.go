@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"product-service/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// querier 同时适用于 *sql.DB 和 *sql.Tx 的查询接口
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanner 同时适用于 *sql.Row 和 *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// productDetailColumns 商品详情查询字段，与 scanProductDetail 对应
+const productDetailColumns = `p.id, p.name, p.description, p.price_amount, p.currency, p.stock, p.category_id,
+	       p.sku, p.gtin, p.image_url, p.weight, p.weight_unit, p.length, p.width, p.height, p.dimension_unit, p.status, p.publish_at, p.published_at, p.created_at, p.updated_at, c.name AS category_name`
+
+// productDetailDest 与 productDetailColumns 对应的扫描目标，可追加额外字段
+func productDetailDest(p *models.ProductDetail) []interface{} {
+	return []interface{}{
+		&p.ID, &p.Name, &p.Description, &p.Price.Amount, &p.Price.Currency, &p.Stock, &p.CategoryID,
+		&p.SKU, &p.GTIN, &p.ImageURL, &p.Weight.Value, &p.Weight.Unit,
+		&p.Dimensions.Length, &p.Dimensions.Width, &p.Dimensions.Height, &p.Dimensions.Unit, &p.Status, &p.PublishAt, &p.PublishedAt, &p.CreatedAt, &p.UpdatedAt, &p.CategoryName,
+	}
+}
+
+func scanProductDetail(row scanner, p *models.ProductDetail) error {
+	return row.Scan(productDetailDest(p)...)
+}
+
+// isDuplicateKeyError 是否为唯一键冲突
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// lockProduct 在事务内锁定未删除的商品行，商品不存在时返回 sql.ErrNoRows
+func lockProduct(tx *sql.Tx, productID int) error {
+	var id int
+	return tx.QueryRow(
+		"SELECT id FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE",
+		productID,
+	).Scan(&id)
+}
+
+// loadProductDetail 加载商品及其属性、图片和价格表，includeDeleted 为true时包含已软删除的商品
+func loadProductDetail(q querier, productID int, includeDeleted bool) (models.ProductDetail, error) {
+	var product models.ProductDetail
+
+	query := `
+		SELECT ` + productDetailColumns + `
+		FROM products p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.id = ?`
+	if !includeDeleted {
+		query += " AND p.deleted_at IS NULL"
+	}
+
+	if err := scanProductDetail(q.QueryRow(query, productID), &product); err != nil {
+		return product, err
+	}
+
+	attributes, err := loadProductAttributes(q, productID)
+	if err != nil {
+		return product, err
+	}
+	product.Attributes = attributes
+
+	images, err := loadProductImages(q, productID)
+	if err != nil {
+		return product, err
+	}
+	product.Images = images
+
+	prices, err := loadPricesByProduct(q, []int{productID})
+	if err != nil {
+		return product, err
+	}
+	product.Prices = prices[productID]
+
+	return product, nil
+}
+
+func loadProductAttributes(q querier, productID int) ([]models.ProductAttribute, error) {
+	rows, err := q.Query(`
+		SELECT id, name, value
+		FROM product_attributes
+		WHERE product_id = ?
+		ORDER BY id
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attributes []models.ProductAttribute
+	for rows.Next() {
+		var attr models.ProductAttribute
+		if err := rows.Scan(&attr.ID, &attr.Name, &attr.Value); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attr)
+	}
+	return attributes, rows.Err()
+}
+
+func loadProductImages(q querier, productID int) ([]models.ProductImage, error) {
+	rows, err := q.Query(`
+		SELECT id, image_url, is_primary
+		FROM product_images
+		WHERE product_id = ?
+		ORDER BY id
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.ProductImage
+	for rows.Next() {
+		var img models.ProductImage
+		if err := rows.Scan(&img.ID, &img.ImageURL, &img.IsPrimary); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// loadAttributesByProduct 用一次 IN 查询批量加载多个商品的属性
+func loadAttributesByProduct(q querier, productIDs []int) (map[int][]models.ProductAttribute, error) {
+	result := make(map[int][]models.ProductAttribute, len(productIDs))
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := q.Query(`
+		SELECT product_id, id, name, value
+		FROM product_attributes
+		WHERE product_id IN `+inPlaceholders(len(productIDs))+`
+		ORDER BY product_id, id
+	`, intsToArgs(productIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int
+		var attr models.ProductAttribute
+		if err := rows.Scan(&productID, &attr.ID, &attr.Name, &attr.Value); err != nil {
+			return nil, err
+		}
+		result[productID] = append(result[productID], attr)
+	}
+	return result, rows.Err()
+}
+
+// loadImagesByProduct 用一次 IN 查询批量加载多个商品的图片
+func loadImagesByProduct(q querier, productIDs []int) (map[int][]models.ProductImage, error) {
+	result := make(map[int][]models.ProductImage, len(productIDs))
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := q.Query(`
+		SELECT product_id, id, image_url, is_primary
+		FROM product_images
+		WHERE product_id IN `+inPlaceholders(len(productIDs))+`
+		ORDER BY product_id, id
+	`, intsToArgs(productIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int
+		var img models.ProductImage
+		if err := rows.Scan(&productID, &img.ID, &img.ImageURL, &img.IsPrimary); err != nil {
+			return nil, err
+		}
+		result[productID] = append(result[productID], img)
+	}
+	return result, rows.Err()
+}
+
+// loadPricesByProduct 用一次 IN 查询批量加载多个商品的价格表
+func loadPricesByProduct(q querier, productIDs []int) (map[int][]models.Money, error) {
+	result := make(map[int][]models.Money, len(productIDs))
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := q.Query(`
+		SELECT product_id, amount, currency
+		FROM product_prices
+		WHERE product_id IN `+inPlaceholders(len(productIDs))+`
+		ORDER BY product_id, currency
+	`, intsToArgs(productIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int
+		var price models.Money
+		if err := rows.Scan(&productID, &price.Amount, &price.Currency); err != nil {
+			return nil, err
+		}
+		result[productID] = append(result[productID], price)
+	}
+	return result, rows.Err()
+}
+
+// loadProductDetailsByID 按ID列表批量加载未删除的商品及其属性、图片和价格表，固定使用四次查询
+func loadProductDetailsByID(q querier, productIDs []int) ([]models.ProductDetail, error) {
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := q.Query(
+		"SELECT "+productDetailColumns+productListFrom+" AND p.id IN "+inPlaceholders(len(productIDs))+" ORDER BY p.id",
+		intsToArgs(productIDs)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var products []models.ProductDetail
+	for rows.Next() {
+		var p models.ProductDetail
+		if err := scanProductDetail(rows, &p); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+	}
+	attributes, err := loadAttributesByProduct(q, ids)
+	if err != nil {
+		return nil, err
+	}
+	images, err := loadImagesByProduct(q, ids)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := loadPricesByProduct(q, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].Prices = prices[products[i].ID]
+		products[i].Attributes = attributes[products[i].ID]
+		products[i].Images = images[products[i].ID]
+	}
+
+	return products, nil
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"math"
+	"product-service/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validateProductMeasures 校验商品的包装重量和尺寸并规范单位
+func validateProductMeasures(p *models.Product) error {
+	if err := p.Weight.Normalize(); err != nil {
+		return err
+	}
+	return p.Dimensions.Normalize()
+}
+
+// measureExpr 将按 unitColumn 单位保存的 valueColumn 换算为基准单位的 SQL 表达式，换算系数来自 factors。
+// 结果与过滤值都保留三位小数，避免 12 in 与 304.8 mm 这样的边界因浮点误差不相等
+func measureExpr(valueColumn, unitColumn string, factors map[string]float64) string {
+	units := make([]string, 0, len(factors))
+	for unit := range factors {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+
+	var b strings.Builder
+	b.WriteString("ROUND(" + valueColumn + " * CASE " + unitColumn)
+	for _, unit := range units {
+		b.WriteString(" WHEN '" + unit + "' THEN " + strconv.FormatFloat(factors[unit], 'f', -1, 64))
+	}
+	b.WriteString(" END, 3)")
+	return b.String()
+}
+
+// measureFilterClauses 生成重量和尺寸的过滤条件，过滤值先换算为克和毫米
+func measureFilterClauses(filter models.ProductFilter) (string, []interface{}) {
+	var args []interface{}
+	where := ""
+
+	if filter.MinWeight > 0 || filter.MaxWeight > 0 {
+		grams := models.WeightUnitGrams[measureFilterUnit(filter.WeightUnit, models.WeightUnitGram)]
+		weight := measureExpr("p.weight", "p.weight_unit", models.WeightUnitGrams)
+		if filter.MinWeight > 0 {
+			where += " AND " + weight + " >= ?"
+			args = append(args, roundFilterMeasure(filter.MinWeight*grams))
+		}
+		if filter.MaxWeight > 0 {
+			where += " AND " + weight + " <= ?"
+			args = append(args, roundFilterMeasure(filter.MaxWeight*grams))
+		}
+	}
+
+	millimetres := models.LengthUnitMillimetres[measureFilterUnit(filter.DimensionUnit, models.LengthUnitMillimetre)]
+	for _, limit := range []struct {
+		column string
+		value  float64
+	}{
+		{"p.length", filter.MaxLength},
+		{"p.width", filter.MaxWidth},
+		{"p.height", filter.MaxHeight},
+	} {
+		if limit.value > 0 {
+			where += " AND " + measureExpr(limit.column, "p.dimension_unit", models.LengthUnitMillimetres) + " <= ?"
+			args = append(args, roundFilterMeasure(limit.value*millimetres))
+		}
+	}
+
+	return where, args
+}
+
+// roundFilterMeasure 过滤值保留三位小数，与 measureExpr 一致
+func roundFilterMeasure(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// measureFilterUnit 过滤条件使用的单位，未指定时为基准单位
+func measureFilterUnit(unit, base string) string {
+	if unit == "" {
+		return base
+	}
+	return unit
+}
+
+// normalizeMeasureUnits 校验请求中的重量和长度单位，空值表示不换算
+func normalizeMeasureUnits(weightUnit, dimensionUnit string) (string, string, error) {
+	var err error
+	if weightUnit != "" {
+		if weightUnit, err = models.NormalizeWeightUnit(weightUnit); err != nil {
+			return "", "", err
+		}
+	}
+	if dimensionUnit != "" {
+		if dimensionUnit, err = models.NormalizeLengthUnit(dimensionUnit); err != nil {
+			return "", "", err
+		}
+	}
+	return weightUnit, dimensionUnit, nil
+}
+
+// applyMeasureUnits 将商品的重量和尺寸换算为请求的单位，单位为空时保持原单位
+func applyMeasureUnits(p *models.ProductDetail, weightUnit, dimensionUnit string) {
+	if weightUnit != "" {
+		p.Weight = p.Weight.In(weightUnit)
+	}
+	if dimensionUnit != "" {
+		p.Dimensions = p.Dimensions.In(dimensionUnit)
+	}
+}
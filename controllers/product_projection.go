@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"product-service/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productFieldNames 可通过 fields 参数选择的商品字段，与 ProductDetail 的 JSON 字段对应
+var productFieldNames = map[string]bool{
+	"id": true, "name": true, "description": true, "price": true, "stock": true,
+	"category_id": true, "sku": true, "gtin": true, "image_url": true, "weight": true, "dimensions": true, "status": true, "publish_at": true,
+	"published_at": true, "created_at": true, "updated_at": true, "category_name": true,
+	"attributes": true, "images": true, "prices": true, "display_price": true,
+	"exchange_rate": true, "promotion": true,
+}
+
+// productIncludes 列表中需要附带加载的关联数据
+type productIncludes struct {
+	attributes bool
+	images     bool
+	prices     bool
+}
+
+// parseProductIncludes 解析 include 参数，如 include=attributes,images,prices
+func parseProductIncludes(value string) (productIncludes, error) {
+	var inc productIncludes
+	for _, part := range strings.Split(value, ",") {
+		switch strings.TrimSpace(part) {
+		case "":
+		case "attributes":
+			inc.attributes = true
+		case "images":
+			inc.images = true
+		case "prices":
+			inc.prices = true
+		default:
+			return inc, fmt.Errorf("unknown include %q", strings.TrimSpace(part))
+		}
+	}
+	return inc, nil
+}
+
+// parseProductFields 解析 fields 参数，未指定时返回 nil 表示输出全部字段。
+// 选择了 attributes、images 或 prices 时自动加载对应的关联数据
+func parseProductFields(value string, inc *productIncludes) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, part := range strings.Split(value, ",") {
+		field := strings.TrimSpace(part)
+		if field == "" {
+			continue
+		}
+		if !productFieldNames[field] {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		switch field {
+		case "attributes":
+			inc.attributes = true
+		case "images":
+			inc.images = true
+		case "prices":
+			inc.prices = true
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// loadProductRelations 用 IN 查询批量加载一页商品的属性和图片，查询次数与商品数量无关
+func loadProductRelations(q querier, products []models.ProductDetail, inc productIncludes) error {
+	if len(products) == 0 || (!inc.attributes && !inc.images && !inc.prices) {
+		return nil
+	}
+
+	ids := make([]int, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+	}
+
+	if inc.attributes {
+		attributes, err := loadAttributesByProduct(q, ids)
+		if err != nil {
+			return err
+		}
+		for i := range products {
+			products[i].Attributes = attributes[products[i].ID]
+		}
+	}
+	if inc.images {
+		images, err := loadImagesByProduct(q, ids)
+		if err != nil {
+			return err
+		}
+		for i := range products {
+			products[i].Images = images[products[i].ID]
+		}
+	}
+	if inc.prices {
+		prices, err := loadPricesByProduct(q, ids)
+		if err != nil {
+			return err
+		}
+		for i := range products {
+			products[i].Prices = prices[products[i].ID]
+		}
+	}
+	return nil
+}
+
+// projectProducts 只保留选中的字段
+func projectProducts(products []models.ProductDetail, fields []string) ([]map[string]json.RawMessage, error) {
+	projected := make([]map[string]json.RawMessage, len(products))
+	for i := range products {
+		data, err := json.Marshal(products[i])
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+
+		item := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			value, ok := all[field]
+			if !ok {
+				// attributes、images 和 prices 为空时被省略，投影时输出空数组；
+				// display_price、exchange_rate 和 promotion 没有时输出 null
+				value = json.RawMessage("[]")
+				if field == "display_price" || field == "exchange_rate" || field == "promotion" {
+					value = json.RawMessage("null")
+				}
+			}
+			item[field] = value
+		}
+		projected[i] = item
+	}
+	return projected, nil
+}
+
+// renderProducts 输出包含 products 字段的响应，指定 fields 时对其中的商品做字段投影
+func renderProducts(c *gin.Context, response interface{}, products []models.ProductDetail, fields []string) {
+	if fields == nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	projected, err := projectProducts(products, fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render products"})
+		return
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render products"})
+		return
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render products"})
+		return
+	}
+	body["products"] = projected
+
+	c.JSON(http.StatusOK, body)
+}
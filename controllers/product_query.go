@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"product-service/models"
+	"product-service/search"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productListFrom 商品列表查询的 FROM 子句，与 buildProductFilter 生成的条件配合使用
+const productListFrom = `
+		FROM products p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.deleted_at IS NULL`
+
+// productQuery 由过滤条件生成的附加 WHERE 条件及参数
+type productQuery struct {
+	where string
+	args  []interface{}
+	// rankedIDs 全文搜索命中的商品ID，按相关度从高到低排列，未搜索时为 nil
+	rankedIDs []int
+	// searchTruncated 搜索命中数超过上限，rankedIDs 只包含相关度最高的部分
+	searchTruncated bool
+	// ignoredTerms 搜索引擎忽略的搜索词
+	ignoredTerms []string
+	// currency 价格过滤和排序使用的币种
+	currency string
+}
+
+// priceExpr 商品在查询币种下价格的 SQL 表达式
+func (q productQuery) priceExpr() string {
+	return productPriceExpr(q.currency)
+}
+
+// productPriceExpr 商品在指定币种下的价格（最小货币单位）：基础币种相同时取商品价格，
+// 否则取价格表中的价格，再否则按汇率换算，都没有时为 -1。currency 须已通过 NormalizeCurrency 校验
+func productPriceExpr(currency string) string {
+	code := "'" + currency + "'"
+	return "(CASE WHEN p.currency = " + code + " THEN p.price_amount" +
+		" ELSE COALESCE((SELECT pp.amount FROM product_prices pp WHERE pp.product_id = p.id AND pp.currency = " + code + "), " +
+		exchangeRates.conversionExpr(currency) + ") END)"
+}
+
+// productListedPriceExpr 商品在指定币种下直接设置的价格，不做汇率换算，没有时为 -1
+func productListedPriceExpr(currency string) string {
+	code := "'" + currency + "'"
+	return "(CASE WHEN p.currency = " + code + " THEN p.price_amount" +
+		" ELSE COALESCE((SELECT pp.amount FROM product_prices pp WHERE pp.product_id = p.id AND pp.currency = " + code + "), -1) END)"
+}
+
+// searching 是否使用了全文搜索
+func (q productQuery) searching() bool {
+	return q.rankedIDs != nil
+}
+
+// relevanceExpr 商品在搜索结果中名次的 SQL 表达式，名次越小越相关
+func (q productQuery) relevanceExpr() string {
+	if len(q.rankedIDs) == 0 {
+		return "0"
+	}
+	ids := make([]string, len(q.rankedIDs))
+	for i, id := range q.rankedIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	return "FIELD(p.id, " + strings.Join(ids, ", ") + ")"
+}
+
+// bindProductFilter 绑定商品过滤参数，属性过滤形如 attr[color]=red,blue
+func bindProductFilter(c *gin.Context) (models.ProductFilter, error) {
+	var filter models.ProductFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		return filter, err
+	}
+
+	if filter.Currency == "" {
+		filter.Currency = models.DefaultCurrency
+	}
+	currency, err := models.NormalizeCurrency(filter.Currency)
+	if err != nil {
+		return filter, err
+	}
+	filter.Currency = currency
+	for _, value := range []string{filter.MinPrice, filter.MaxPrice} {
+		if value == "" {
+			continue
+		}
+		if _, err := models.ParseMoney(value, currency); err != nil {
+			return filter, err
+		}
+	}
+
+	// gte=0 不能排除 +Inf，无穷大传给 MySQL 会出错
+	measures := []struct {
+		name  string
+		value float64
+	}{
+		{"min_weight", filter.MinWeight}, {"max_weight", filter.MaxWeight},
+		{"max_length", filter.MaxLength}, {"max_width", filter.MaxWidth}, {"max_height", filter.MaxHeight},
+	}
+	for _, m := range measures {
+		if math.IsNaN(m.value) || math.IsInf(m.value, 0) {
+			return filter, fmt.Errorf("%s must be a finite number", m.name)
+		}
+	}
+	if filter.WeightUnit, filter.DimensionUnit, err = normalizeMeasureUnits(filter.WeightUnit, filter.DimensionUnit); err != nil {
+		return filter, err
+	}
+
+	// attr_min[name]、attr_max[name] 为数值属性的范围
+	for bound, values := range map[string]map[string]string{"min": c.QueryMap("attr_min"), "max": c.QueryMap("attr_max")} {
+		for name, value := range values {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return filter, errors.New("attribute range name must not be empty")
+			}
+			number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				return filter, fmt.Errorf("invalid attr_%s[%s] %q", bound, name, value)
+			}
+			if filter.AttributeRanges == nil {
+				filter.AttributeRanges = map[string]models.AttributeRange{}
+			}
+			r := filter.AttributeRanges[name]
+			if bound == "min" {
+				r.Min = &number
+			} else {
+				r.Max = &number
+			}
+			filter.AttributeRanges[name] = r
+		}
+	}
+
+	for name, value := range c.QueryMap("attr") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return filter, errors.New("attribute filter name must not be empty")
+		}
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = map[string][]string{}
+		}
+		filter.Attributes[name] = values
+	}
+
+	return filter, nil
+}
+
+// rank 商品在搜索结果中的名次，从1开始
+func (q productQuery) rank(productID int) int {
+	for i, id := range q.rankedIDs {
+		if id == productID {
+			return i + 1
+		}
+	}
+	return len(q.rankedIDs) + 1
+}
+
+// buildProductFilter 根据过滤条件生成附加的 WHERE 条件及参数
+func buildProductFilter(filter models.ProductFilter) (productQuery, error) {
+	query := productQuery{currency: filterCurrency(filter)}
+	if q := searchDictionary.Apply(search.ParseQuery(filter.Search)); !q.Empty() {
+		// 状态在搜索引擎内过滤，避免未发布的商品占满 searchMaxResults 个结果
+		if filter.Status != "all" {
+			q.Status = filter.Status
+		}
+		ids, truncated, err := searchProductIDs(q)
+		if err != nil {
+			return productQuery{}, err
+		}
+		query.rankedIDs = append([]int{}, ids...)
+		query.searchTruncated = truncated
+		query.ignoredTerms = searchEngine.IgnoredTerms(q)
+	}
+
+	query.where, query.args = productFilterClauses(filter, query.rankedIDs)
+	return query, nil
+}
+
+// productFilterClauses 生成过滤条件，rankedIDs 为 nil 表示不限制搜索结果
+func productFilterClauses(filter models.ProductFilter, rankedIDs []int) (string, []interface{}) {
+	var args []interface{}
+	where := ""
+
+	if filter.CategoryID > 0 {
+		where += " AND p.category_id = ?"
+		args = append(args, filter.CategoryID)
+	}
+	if filter.Status != "" && filter.Status != "all" {
+		where += " AND p.status = ?"
+		args = append(args, filter.Status)
+	}
+	// 价格条件按查询币种比较，没有该币种价格的商品不参与价格过滤
+	if filter.MinPrice != "" || filter.MaxPrice != "" {
+		currency := filterCurrency(filter)
+		price := productPriceExpr(currency)
+		where += " AND " + price + " >= 0"
+		if minPrice, err := models.ParseMoney(filter.MinPrice, currency); err == nil {
+			where += " AND " + price + " >= ?"
+			args = append(args, minPrice.Amount)
+		}
+		if maxPrice, err := models.ParseMoney(filter.MaxPrice, currency); err == nil {
+			where += " AND " + price + " <= ?"
+			args = append(args, maxPrice.Amount)
+		}
+	}
+
+	measureWhere, measureArgs := measureFilterClauses(filter)
+	where += measureWhere
+	args = append(args, measureArgs...)
+
+	// 同一属性的多个值为“或”，不同属性之间为“且”
+	names := make([]string, 0, len(filter.Attributes))
+	for name := range filter.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := filter.Attributes[name]
+		where += " AND EXISTS (SELECT 1 FROM product_attributes fa WHERE fa.product_id = p.id AND fa.name = ? AND fa.value IN " +
+			inPlaceholders(len(values)) + ")"
+		args = append(args, name)
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+
+	// 数值范围只匹配属性值为数值的商品
+	rangeNames := make([]string, 0, len(filter.AttributeRanges))
+	for name := range filter.AttributeRanges {
+		rangeNames = append(rangeNames, name)
+	}
+	sort.Strings(rangeNames)
+	for _, name := range rangeNames {
+		r := filter.AttributeRanges[name]
+		where += " AND EXISTS (SELECT 1 FROM product_attributes fr WHERE fr.product_id = p.id AND fr.name = ? AND fr.number_value IS NOT NULL"
+		args = append(args, name)
+		if r.Min != nil {
+			where += " AND fr.number_value >= ?"
+			args = append(args, *r.Min)
+		}
+		if r.Max != nil {
+			where += " AND fr.number_value <= ?"
+			args = append(args, *r.Max)
+		}
+		where += ")"
+	}
+
+	if rankedIDs != nil {
+		if len(rankedIDs) == 0 {
+			where += " AND 1 = 0"
+		} else {
+			where += " AND p.id IN " + inPlaceholders(len(rankedIDs))
+			args = append(args, intsToArgs(rankedIDs)...)
+		}
+	}
+
+	return where, args
+}
+
+// filterCurrency 过滤条件使用的币种，未绑定时为默认币种
+func filterCurrency(filter models.ProductFilter) string {
+	if currency, err := models.NormalizeCurrency(filter.Currency); err == nil {
+		return currency
+	}
+	return models.DefaultCurrency
+}
+
+// inPlaceholders 生成 IN 子句的占位符，如 (?, ?, ?)
+func inPlaceholders(n int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+}
+
+func intsToArgs(values []int) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
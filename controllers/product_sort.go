@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"product-service/models"
+	"strings"
+	"time"
+)
+
+// productSortField 可排序字段，value 取出商品在该字段上的值用于生成游标
+type productSortField struct {
+	column string
+	value  func(p *models.ProductDetail) interface{}
+	// decode 将游标中 JSON 解码后的值还原为查询参数
+	decode func(v interface{}) (interface{}, bool)
+}
+
+var productSortFields = map[string]productSortField{
+	"price":      {"", displayPriceAmount, decodeCursorNumber},
+	"name":       {"p.name", func(p *models.ProductDetail) interface{} { return p.Name }, decodeCursorString},
+	"created_at": {"p.created_at", func(p *models.ProductDetail) interface{} { return p.CreatedAt }, decodeCursorTime},
+	"updated_at": {"p.updated_at", func(p *models.ProductDetail) interface{} { return p.UpdatedAt }, decodeCursorTime},
+	"stock":      {"p.stock", func(p *models.ProductDetail) interface{} { return p.Stock }, decodeCursorNumber},
+	"id":         {"p.id", func(p *models.ProductDetail) interface{} { return p.ID }, decodeCursorNumber},
+}
+
+// 相关度排序使用商品在搜索结果中的名次，名次越小越相关
+const sortRelevance = "relevance"
+
+// 价格排序使用商品在查询币种下的价格，没有该币种价格时为 -1
+const sortPrice = "price"
+
+func displayPriceAmount(p *models.ProductDetail) interface{} {
+	if p.DisplayPrice == nil {
+		return -1
+	}
+	return p.DisplayPrice.Amount
+}
+
+type productSortKey struct {
+	field string
+	desc  bool
+}
+
+// productSort 解析后的排序规则，最后总是以商品ID作为决胜键保证顺序稳定
+type productSort struct {
+	keys  []productSortKey
+	query productQuery
+}
+
+// parseProductSort 解析 sort 参数，多个键以逗号分隔，形如 price,-name 或 price:asc,name:desc。
+// 未指定时搜索结果按相关度排序，否则按商品ID排序
+func parseProductSort(param string, query productQuery) (productSort, error) {
+	s := productSort{query: query}
+
+	param = strings.TrimSpace(param)
+	if param == "" && query.searching() {
+		param = sortRelevance
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key := productSortKey{field: part}
+		if strings.HasPrefix(part, "-") {
+			key = productSortKey{field: part[1:], desc: true}
+		} else if name, dir, ok := strings.Cut(part, ":"); ok {
+			key.field = name
+			switch strings.ToLower(dir) {
+			case "asc":
+			case "desc":
+				key.desc = true
+			default:
+				return s, fmt.Errorf("invalid sort direction %q", dir)
+			}
+		}
+
+		if key.field == "id" {
+			return s, fmt.Errorf("unknown sort field %q", key.field)
+		}
+		if _, ok := productSortFields[key.field]; !ok && key.field != sortRelevance {
+			return s, fmt.Errorf("unknown sort field %q", key.field)
+		}
+		if seen[key.field] {
+			return s, fmt.Errorf("duplicate sort field %q", key.field)
+		}
+		seen[key.field] = true
+
+		// 没有搜索条件时所有商品相关度相同，忽略该键
+		if key.field == sortRelevance && !query.searching() {
+			continue
+		}
+		s.keys = append(s.keys, key)
+	}
+
+	s.keys = append(s.keys, productSortKey{field: "id"})
+	return s, nil
+}
+
+func (s productSort) expr(key productSortKey) string {
+	switch key.field {
+	case sortRelevance:
+		return s.query.relevanceExpr()
+	case sortPrice:
+		return s.query.priceExpr()
+	}
+	return productSortFields[key.field].column
+}
+
+// spec 排序规则的规范形式，写入游标用于校验
+func (s productSort) spec() string {
+	parts := make([]string, len(s.keys))
+	for i, key := range s.keys {
+		parts[i] = key.field
+		if key.desc {
+			parts[i] = "-" + key.field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// orderBy 生成 ORDER BY 子句
+func (s productSort) orderBy() string {
+	parts := make([]string, len(s.keys))
+	for i, key := range s.keys {
+		parts[i] = s.expr(key)
+		// 相关度名次越小越相关，降序（最相关在前）对应名次升序
+		if key.desc != (key.field == sortRelevance) {
+			parts[i] += " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// keysetCondition 生成位于游标之后的条件：
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func (s productSort) keysetCondition(values []interface{}) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, key := range s.keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, s.expr(s.keys[j])+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if key.desc != (key.field == sortRelevance) {
+			op = " < ?"
+		}
+		ands = append(ands, s.expr(key)+op)
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return " AND (" + strings.Join(ors, " OR ") + ")", args
+}
+
+// productCursor 游标内容，使用 base64 编码后对调用方不透明
+type productCursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// encodeCursor 根据当前页最后一个商品生成下一页的游标
+func (s productSort) encodeCursor(p *models.ProductDetail) string {
+	cursor := productCursor{Sort: s.spec()}
+	for _, key := range s.keys {
+		if key.field == sortRelevance {
+			cursor.Values = append(cursor.Values, s.query.rank(p.ID))
+			continue
+		}
+		value := productSortFields[key.field].value(p)
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano)
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// decodeCursor 解析游标并校验与当前排序规则一致
+func (s productSort) decodeCursor(value string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor productCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errInvalidCursor
+	}
+	if cursor.Sort != s.spec() {
+		return nil, errors.New("cursor does not match sort order")
+	}
+	if len(cursor.Values) != len(s.keys) {
+		return nil, errInvalidCursor
+	}
+
+	values := make([]interface{}, len(s.keys))
+	for i, key := range s.keys {
+		decode := decodeCursorNumber
+		if key.field != sortRelevance {
+			decode = productSortFields[key.field].decode
+		}
+		v, ok := decode(cursor.Values[i])
+		if !ok {
+			return nil, errInvalidCursor
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func decodeCursorNumber(v interface{}) (interface{}, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+func decodeCursorString(v interface{}) (interface{}, bool) {
+	s, ok := v.(string)
+	return s, ok
+}
+
+func decodeCursorTime(v interface{}) (interface{}, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
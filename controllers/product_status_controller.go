@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productStatusTransitions 商品状态允许的转换：草稿发布或定时发布，发布后归档，归档后回到草稿
+var productStatusTransitions = map[string][]string{
+	models.ProductStatusDraft:     {models.ProductStatusScheduled, models.ProductStatusPublished, models.ProductStatusArchived},
+	models.ProductStatusScheduled: {models.ProductStatusDraft, models.ProductStatusScheduled, models.ProductStatusPublished, models.ProductStatusArchived},
+	models.ProductStatusPublished: {models.ProductStatusArchived},
+	models.ProductStatusArchived:  {models.ProductStatusDraft},
+}
+
+// 每次检查最多发布的定时商品数量
+const publishBatchSize = 100
+
+func canTransitionProductStatus(from, to string) bool {
+	for _, status := range productStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// canViewUnpublished 只有管理员可以查看未发布的商品，普通客户的令牌不授予该权限
+func canViewUnpublished(c *gin.Context) bool {
+	return middlewares.IsAdmin(c)
+}
+
+// validatePublishAt 校验定时发布时间，只有 scheduled 状态需要且必须晚于 now
+func validatePublishAt(status string, publishAt *time.Time, now time.Time) error {
+	if status != models.ProductStatusScheduled {
+		if publishAt != nil {
+			return errors.New("publish_at is only allowed for scheduled products")
+		}
+		return nil
+	}
+	if publishAt == nil || !publishAt.After(now) {
+		return errors.New("publish_at must be in the future for scheduled products")
+	}
+	return nil
+}
+
+// normalizeNewProductStatus 校验新建商品的状态，未指定时为草稿，新建商品不能直接归档
+func normalizeNewProductStatus(p *models.Product, now time.Time) error {
+	if p.Status == "" {
+		p.Status = models.ProductStatusDraft
+	}
+	switch p.Status {
+	case models.ProductStatusDraft, models.ProductStatusScheduled, models.ProductStatusPublished:
+	default:
+		return fmt.Errorf("status must be one of %s, %s, %s",
+			models.ProductStatusDraft, models.ProductStatusScheduled, models.ProductStatusPublished)
+	}
+	if err := validatePublishAt(p.Status, p.PublishAt, now); err != nil {
+		return err
+	}
+
+	p.PublishedAt = nil
+	if p.Status == models.ProductStatusPublished {
+		publishedAt := now
+		p.PublishedAt = &publishedAt
+	}
+	return nil
+}
+
+// productStatusEvent 商品状态变为 status 时发送的事件
+func productStatusEvent(status string) string {
+	switch status {
+	case models.ProductStatusPublished:
+		return models.EventProductPublished
+	case models.ProductStatusArchived:
+		return models.EventProductArchived
+	}
+	return models.EventProductUpdated
+}
+
+// lockProductStatus 在事务内锁定未删除的商品行并返回当前状态，商品不存在时返回 sql.ErrNoRows
+func lockProductStatus(tx *sql.Tx, productID int) (string, *time.Time, error) {
+	var status string
+	var publishAt sql.NullTime
+	err := tx.QueryRow(
+		"SELECT status, publish_at FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE",
+		productID,
+	).Scan(&status, &publishAt)
+	if err != nil || !publishAt.Valid {
+		return status, nil, err
+	}
+	return status, &publishAt.Time, nil
+}
+
+// setProductStatus 修改已锁定商品的状态并记录修订，发布时同时更新发布时间
+func setProductStatus(tx *sql.Tx, productID int, status string, publishAt *time.Time, actorID int, now time.Time) error {
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		return err
+	}
+
+	query := "UPDATE products SET status = ?, publish_at = ?, updated_at = NOW()"
+	args := []interface{}{status, publishAt}
+	if status == models.ProductStatusPublished {
+		query += ", published_at = ?"
+		args = append(args, now)
+	}
+	if _, err := tx.Exec(query+" WHERE id = ?", append(args, productID)...); err != nil {
+		return err
+	}
+
+	action := models.RevisionActionChangeStatus
+	switch status {
+	case models.ProductStatusPublished:
+		action = models.RevisionActionPublish
+	case models.ProductStatusArchived:
+		action = models.RevisionActionArchive
+	}
+	_, err := recordRevision(tx, productID, action, actorID)
+	return err
+}
+
+// SetProductStatus 按状态机修改商品状态，PUT /products/:id/status
+func SetProductStatus(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("set_status", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req models.ProductStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	if err := validatePublishAt(req.Status, req.PublishAt, now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	current, _, err := lockProductStatus(tx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !canTransitionProductStatus(current, req.Status) {
+		allowed := productStatusTransitions[current]
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   fmt.Sprintf("Cannot change status from %s to %s", current, req.Status),
+			"status":  current,
+			"allowed": allowed,
+		})
+		return
+	}
+	// 发布或定时发布前须设置分类要求的全部属性
+	if req.Status == models.ProductStatusPublished || req.Status == models.ProductStatusScheduled {
+		var categoryID int
+		if err := tx.QueryRow("SELECT category_id FROM products WHERE id = ?", productID).Scan(&categoryID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		missing, err := missingRequiredAttributes(tx, productID, categoryID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if len(missing) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Missing required attributes", "missing_attributes": missing})
+			return
+		}
+	}
+
+	if err := setProductStatus(tx, productID, req.Status, req.PublishAt, c.GetInt("userID"), now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product status"})
+		return
+	}
+
+	product, err := loadProductDetail(tx, productID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "product", productID)
+	if rabbitMQ != nil {
+		sendProductEvent(productStatusEvent(req.Status), productID, product.Product)
+	}
+	reindexProducts(productID)
+
+	c.JSON(http.StatusOK, product)
+}
+
+// StartProductPublisher 定期发布到达发布时间的定时商品
+func StartProductPublisher(interval time.Duration) {
+	publishScheduledProducts()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		publishScheduledProducts()
+	}
+}
+
+func publishScheduledProducts() {
+	now := time.Now()
+	rows, err := database.DB.Query(`
+		SELECT id FROM products
+		WHERE status = ? AND publish_at <= ? AND deleted_at IS NULL
+		ORDER BY publish_at, id LIMIT ?
+	`, models.ProductStatusScheduled, now, publishBatchSize)
+	if err != nil {
+		log.Printf("Failed to query scheduled products: %v", err)
+		return
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("Failed to scan scheduled product: %v", err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	var published []string
+	for _, id := range ids {
+		ok, err := publishScheduledProduct(id, now)
+		if err != nil {
+			log.Printf("Failed to publish scheduled product %d: %v", id, err)
+			continue
+		}
+		if ok {
+			published = append(published, strconv.Itoa(id))
+		}
+	}
+	if len(published) > 0 {
+		log.Printf("Published scheduled products: %s", strings.Join(published, ", "))
+	}
+}
+
+// publishScheduledProduct 在事务内重新检查状态后发布定时商品，多个副本同时运行时只有一个副本发布
+func publishScheduledProduct(productID int, now time.Time) (bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	status, publishAt, err := lockProductStatus(tx, productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if status != models.ProductStatusScheduled || publishAt == nil || publishAt.After(now) {
+		return false, nil
+	}
+
+	if err := setProductStatus(tx, productID, models.ProductStatusPublished, nil, 0, now); err != nil {
+		return false, err
+	}
+	product, err := loadProductDetail(tx, productID, false)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventProductPublished, productID, product.Product)
+	}
+	reindexProducts(productID)
+	return true, nil
+}
@@ -0,0 +1,527 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"product-service/promotions"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// promotionEngine 展示商品时使用的促销规则，各副本从数据库加载
+var promotionEngine = promotions.NewEngine()
+
+const promotionColumns = `id, name, description, scope, discount_type, percent_off, amount_off, amount_currency,
+	buy_quantity, free_quantity, starts_at, ends_at, priority, stackable, enabled, state, created_at, updated_at`
+
+func scanPromotion(s scanner) (models.Promotion, error) {
+	var p models.Promotion
+	var scope []byte
+	var percent int64
+	var amount sql.NullInt64
+	var currency sql.NullString
+	var startsAt, endsAt sql.NullTime
+	err := s.Scan(
+		&p.ID, &p.Name, &p.Description, &scope, &p.DiscountType, &percent, &amount, &currency,
+		&p.BuyQuantity, &p.FreeQuantity, &startsAt, &endsAt, &p.Priority, &p.Stackable, &p.Enabled, &p.State,
+		&p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(scope, &p.Scope); err != nil {
+		return p, err
+	}
+	if percent > 0 {
+		p.PercentOff = formatPercent(percent)
+	}
+	if amount.Valid && currency.Valid {
+		p.AmountOff = &models.Money{Amount: amount.Int64, Currency: currency.String}
+	}
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	return p, nil
+}
+
+// queryPromotions 按条件查询促销，where 为空时返回全部
+func queryPromotions(q querier, where string, args ...interface{}) ([]models.Promotion, error) {
+	rows, err := q.Query("SELECT "+promotionColumns+" FROM promotions "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Promotion{}
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// formatPercent 将万分比格式化为百分比，如 1250 为 12.5
+func formatPercent(basisPoints int64) json.Number {
+	s := strconv.FormatInt(basisPoints/100, 10)
+	if frac := basisPoints % 100; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%02d", frac), "0")
+	}
+	return json.Number(s)
+}
+
+// normalizePromotion 校验促销规则，并清空与适用范围和优惠方式无关的字段
+func normalizePromotion(p *models.Promotion) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("name must not be empty")
+	}
+
+	scope := &p.Scope
+	categoryIDs, productIDs, skus, attributes := scope.CategoryIDs, scope.ProductIDs, scope.SKUs, scope.Attributes
+	scope.CategoryIDs, scope.ProductIDs, scope.SKUs, scope.Attributes = nil, nil, nil, nil
+	switch scope.Type {
+	case models.PromotionScopeAll:
+	case models.PromotionScopeCategory:
+		for _, id := range categoryIDs {
+			if id <= 0 {
+				return fmt.Errorf("invalid category ID %d", id)
+			}
+		}
+		if len(categoryIDs) == 0 {
+			return errors.New("scope.category_ids must not be empty")
+		}
+		scope.CategoryIDs = categoryIDs
+	case models.PromotionScopeProduct:
+		for _, id := range productIDs {
+			if id <= 0 {
+				return fmt.Errorf("invalid product ID %d", id)
+			}
+		}
+		for _, sku := range skus {
+			if sku = strings.TrimSpace(sku); sku != "" {
+				scope.SKUs = append(scope.SKUs, sku)
+			}
+		}
+		if len(productIDs) == 0 && len(scope.SKUs) == 0 {
+			return errors.New("scope.product_ids or scope.skus must not be empty")
+		}
+		scope.ProductIDs = productIDs
+	case models.PromotionScopeAttribute:
+		for _, attr := range attributes {
+			attr.ID = 0
+			attr.Name, attr.Value = strings.TrimSpace(attr.Name), strings.TrimSpace(attr.Value)
+			if attr.Name == "" || attr.Value == "" {
+				return errors.New("scope.attributes must have a name and a value")
+			}
+			scope.Attributes = append(scope.Attributes, attr)
+		}
+		if len(scope.Attributes) == 0 {
+			return errors.New("scope.attributes must not be empty")
+		}
+	default:
+		return fmt.Errorf("invalid scope type %q", scope.Type)
+	}
+
+	switch p.DiscountType {
+	case models.PromotionPercent:
+		if _, err := promotions.ParsePercent(p.PercentOff); err != nil {
+			return err
+		}
+		p.AmountOff, p.BuyQuantity, p.FreeQuantity = nil, 0, 0
+	case models.PromotionFixed:
+		if p.AmountOff == nil || !p.AmountOff.IsPositive() {
+			return errors.New("amount_off must be greater than zero")
+		}
+		p.PercentOff, p.BuyQuantity, p.FreeQuantity = "", 0, 0
+	case models.PromotionBuyXGetY:
+		if p.BuyQuantity < 1 || p.FreeQuantity < 1 {
+			return errors.New("buy_quantity and free_quantity must be at least 1")
+		}
+		p.PercentOff, p.AmountOff = "", nil
+	default:
+		return fmt.Errorf("invalid discount type %q", p.DiscountType)
+	}
+
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// promotionValues 促销规则写入数据库的字段值，顺序与 INSERT/UPDATE 语句一致
+func promotionValues(p *models.Promotion) []interface{} {
+	scope, _ := json.Marshal(p.Scope)
+	var percent int64
+	if p.DiscountType == models.PromotionPercent {
+		percent, _ = promotions.ParsePercent(p.PercentOff)
+	}
+	var amount, currency interface{}
+	if p.AmountOff != nil {
+		amount, currency = p.AmountOff.Amount, p.AmountOff.Currency
+	}
+	return []interface{}{
+		p.Name, p.Description, scope, p.DiscountType, percent, amount, currency,
+		p.BuyQuantity, p.FreeQuantity, p.StartsAt, p.EndsAt, p.Priority, p.Stackable, p.Enabled,
+	}
+}
+
+// loadPromotions 从数据库重新加载促销规则
+func loadPromotions() error {
+	list, err := queryPromotions(database.DB, "")
+	if err != nil {
+		return err
+	}
+	promotionEngine.Load(list)
+	return nil
+}
+
+// StartPromotionScheduler 启动时加载促销规则，并定期重新加载和切换促销状态
+func StartPromotionScheduler(interval time.Duration) {
+	runPromotionScheduler()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		runPromotionScheduler()
+	}
+}
+
+func runPromotionScheduler() {
+	if err := loadPromotions(); err != nil {
+		log.Printf("Failed to load promotions: %v", err)
+	}
+	runPromotionTransitions(time.Now())
+}
+
+// runPromotionTransitions 按生效时间切换促销状态。促销价格在展示时按时间实时计算，
+// 状态只用于保证每次进入、离开生效期各发送一次事件
+func runPromotionTransitions(now time.Time) {
+	// 整个生效期都已错过的促销直接结束，不发送事件
+	_, err := database.DB.Exec(`
+		UPDATE promotions SET state = ?
+		WHERE state = ? AND ends_at IS NOT NULL AND ends_at <= ?
+	`, models.PromotionEnded, models.PromotionPending, now)
+	if err != nil {
+		log.Printf("Failed to skip missed promotions: %v", err)
+	}
+
+	// 停用、到期或开始时间被推迟的促销结束
+	ended := transitionPromotions(models.PromotionRunning, models.PromotionEnded, models.EventPromotionEnded,
+		"NOT enabled OR (ends_at IS NOT NULL AND ends_at <= ?) OR (starts_at IS NOT NULL AND starts_at > ?)", now, now)
+
+	// 重新启用或延长了生效期的促销重新等待开始
+	_, err = database.DB.Exec(`
+		UPDATE promotions SET state = ?
+		WHERE state = ? AND enabled AND (ends_at IS NULL OR ends_at > ?)
+	`, models.PromotionPending, models.PromotionEnded, now)
+	if err != nil {
+		log.Printf("Failed to reset promotions: %v", err)
+	}
+
+	started := transitionPromotions(models.PromotionPending, models.PromotionRunning, models.EventPromotionStarted,
+		"enabled AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now)
+
+	if ended+started > 0 {
+		log.Printf("Promotions: %d started, %d ended", started, ended)
+	}
+}
+
+// transitionPromotions 将满足 condition 的促销从 from 切换为 to 并发送事件。
+// 更新时再次检查状态和条件，多个副本同时运行时每次切换只有一个副本发送事件
+func transitionPromotions(from, to, eventType, condition string, args ...interface{}) int {
+	due, err := queryPromotions(database.DB, "WHERE state = ? AND ("+condition+")", append([]interface{}{from}, args...)...)
+	if err != nil {
+		log.Printf("Failed to query due promotions: %v", err)
+		return 0
+	}
+
+	count := 0
+	for _, p := range due {
+		result, err := database.DB.Exec(
+			"UPDATE promotions SET state = ? WHERE id = ? AND state = ? AND ("+condition+")",
+			append([]interface{}{to, p.ID, from}, args...)...,
+		)
+		if err != nil {
+			log.Printf("Failed to update promotion %d: %v", p.ID, err)
+			continue
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		p.State = to
+		if rabbitMQ != nil {
+			sendProductEvent(eventType, 0, p)
+		}
+		count++
+	}
+	return count
+}
+
+// HandlePromotionEvent 其他副本修改促销规则后重新加载
+func HandlePromotionEvent(event models.ProductEvent) {
+	if event.EventType != models.EventPromotionsUpdated {
+		return
+	}
+	if err := loadPromotions(); err != nil {
+		log.Printf("Failed to reload promotions: %v", err)
+	}
+}
+
+// promotionsChanged 本副本立即重新加载并切换状态，同时通知其他副本
+func promotionsChanged() {
+	if err := loadPromotions(); err != nil {
+		log.Printf("Failed to reload promotions: %v", err)
+	}
+	sendBroadcastEvent(models.EventPromotionsUpdated)
+	runPromotionTransitions(time.Now())
+}
+
+// applyPromotion 计算商品展示价格上的促销，没有展示价格时按基础价格计算
+func applyPromotion(p *models.ProductDetail, attributes []models.ProductAttribute, now time.Time) {
+	price := p.Price
+	if p.DisplayPrice != nil {
+		price = *p.DisplayPrice
+	}
+	item := promotions.Item{ProductID: p.ID, CategoryID: p.CategoryID, SKU: p.SKU, Attributes: attributes}
+	p.Promotion = promotionEngine.Apply(item, price, now, exchangeRates.convert)
+}
+
+// applyPromotions 计算一页商品的促销。存在按属性匹配的促销而商品未加载属性时，单独批量加载属性
+func applyPromotions(q querier, products []models.ProductDetail, attributesLoaded bool) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	var attributes map[int][]models.ProductAttribute
+	if !attributesLoaded && promotionEngine.NeedsAttributes() {
+		ids := make([]int, len(products))
+		for i := range products {
+			ids[i] = products[i].ID
+		}
+		var err error
+		if attributes, err = loadAttributesByProduct(q, ids); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for i := range products {
+		attrs := products[i].Attributes
+		if attributes != nil {
+			attrs = attributes[products[i].ID]
+		}
+		applyPromotion(&products[i], attrs, now)
+	}
+	return nil
+}
+
+// ListPromotions 列出促销，可按 state 过滤
+func ListPromotions(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("list_promotions", status)
+	}()
+
+	where, args := "", []interface{}{}
+	if state := c.Query("state"); state != "" {
+		switch state {
+		case models.PromotionPending, models.PromotionRunning, models.PromotionEnded:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "state must be one of pending, running, ended"})
+			return
+		}
+		where, args = "WHERE state = ?", append(args, state)
+	}
+
+	list, err := queryPromotions(database.DB, where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"promotions": list})
+}
+
+// GetPromotion 获取单个促销
+func GetPromotion(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("get_promotion", status)
+	}()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	p, err := scanPromotion(database.DB.QueryRow("SELECT "+promotionColumns+" FROM promotions WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// CreatePromotion 新增促销，enabled 未指定时默认启用
+func CreatePromotion(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("create_promotion", status)
+	}()
+
+	p := models.Promotion{Enabled: true}
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizePromotion(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO promotions (name, description, scope, discount_type, percent_off, amount_off, amount_currency,
+			buy_quantity, free_quantity, starts_at, ends_at, priority, stackable, enabled, state)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, append(promotionValues(&p), models.PromotionPending)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promotion"})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	middlewares.SetAuditEntity(c, "promotion", id)
+	promotionsChanged()
+
+	created, err := scanPromotion(database.DB.QueryRow("SELECT "+promotionColumns+" FROM promotions WHERE id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusCreated, gin.H{"id": id})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdatePromotion 整体替换促销规则，状态由调度按新的生效期切换
+func UpdatePromotion(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("update_promotion", status)
+	}()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	p := models.Promotion{Enabled: true}
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizePromotion(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists bool
+	if err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM promotions WHERE id = ?)", id).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE promotions SET name = ?, description = ?, scope = ?, discount_type = ?, percent_off = ?,
+			amount_off = ?, amount_currency = ?, buy_quantity = ?, free_quantity = ?, starts_at = ?, ends_at = ?,
+			priority = ?, stackable = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, append(promotionValues(&p), id)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promotion"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "promotion", id)
+	promotionsChanged()
+
+	updated, err := scanPromotion(database.DB.QueryRow("SELECT "+promotionColumns+" FROM promotions WHERE id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Promotion updated"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeletePromotion 删除促销，正在生效的促销同时发送 promotion_ended 事件
+func DeletePromotion(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("delete_promotion", status)
+	}()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	p, err := scanPromotion(database.DB.QueryRow("SELECT "+promotionColumns+" FROM promotions WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 先结束再删除，与调度任务竞争时只有一方发送结束事件
+	result, err := database.DB.Exec(
+		"UPDATE promotions SET state = ? WHERE id = ? AND state = ?",
+		models.PromotionEnded, id, models.PromotionRunning,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete promotion"})
+		return
+	}
+	wasRunning, _ := result.RowsAffected()
+
+	if _, err := database.DB.Exec("DELETE FROM promotions WHERE id = ?", id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete promotion"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "promotion", id)
+	if wasRunning > 0 && rabbitMQ != nil {
+		p.State = models.PromotionEnded
+		sendProductEvent(models.EventPromotionEnded, 0, p)
+	}
+	promotionsChanged()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted"})
+}
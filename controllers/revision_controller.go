@@ -0,0 +1,536 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"product-service/utils"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordRevision 在事务内为商品记录一条修订，返回新的修订号
+// 普通更新没有产生任何变化时不记录，返回0
+func recordRevision(tx *sql.Tx, productID int, action string, actorID int) (int, error) {
+	snapshot, err := loadProductDetail(tx, productID, true)
+	if err != nil {
+		return 0, err
+	}
+
+	var lastRevision int
+	var lastSnapshot []byte
+	err = tx.QueryRow(`
+		SELECT revision, snapshot
+		FROM product_revisions
+		WHERE product_id = ?
+		ORDER BY revision DESC
+		LIMIT 1
+		FOR UPDATE
+	`, productID).Scan(&lastRevision, &lastSnapshot)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	previous := map[string]interface{}{}
+	if lastSnapshot != nil {
+		var prev models.ProductDetail
+		if err := json.Unmarshal(lastSnapshot, &prev); err != nil {
+			return 0, err
+		}
+		previous = flattenSnapshot(prev)
+	}
+
+	diff := diffSnapshots(previous, flattenSnapshot(snapshot))
+	if len(diff) == 0 && action == models.RevisionActionUpdate {
+		return 0, nil
+	}
+
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return 0, err
+	}
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return 0, err
+	}
+
+	revision := lastRevision + 1
+	_, err = tx.Exec(`
+		INSERT INTO product_revisions (product_id, revision, action, actor_id, snapshot, diff)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, productID, revision, action, actorID, snapshotJSON, diffJSON)
+	if err != nil {
+		return 0, err
+	}
+
+	return revision, nil
+}
+
+// ensureBaselineRevision 为功能上线前创建的商品补记一条基线修订，须在修改商品之前调用
+func ensureBaselineRevision(tx *sql.Tx, productID int) error {
+	var exists bool
+	err := tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM product_revisions WHERE product_id = ?)",
+		productID,
+	).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = recordRevision(tx, productID, models.RevisionActionBaseline, 0)
+	return err
+}
+
+// flattenSnapshot 将快照展开为 字段名 -> 值 的映射，属性和图片按ID展开
+func flattenSnapshot(p models.ProductDetail) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	data, err := json.Marshal(p.Product)
+	if err == nil {
+		_ = json.Unmarshal(data, &fields)
+	}
+	delete(fields, "updated_at")
+	fields["category_name"] = p.CategoryName
+
+	for _, attr := range p.Attributes {
+		fields[fmt.Sprintf("attributes.%d", attr.ID)] = map[string]interface{}{
+			"name":  attr.Name,
+			"value": attr.Value,
+		}
+	}
+	for _, img := range p.Images {
+		fields[fmt.Sprintf("images.%d", img.ID)] = map[string]interface{}{
+			"image_url":  img.ImageURL,
+			"is_primary": img.IsPrimary,
+		}
+	}
+	for _, price := range p.Prices {
+		fields["prices."+price.Currency] = price.Decimal()
+	}
+
+	return fields
+}
+
+// diffSnapshots 比较两个展开后的快照，按字段名排序返回变化
+func diffSnapshots(from, to map[string]interface{}) []models.FieldChange {
+	changes := []models.FieldChange{}
+
+	for field, newValue := range to {
+		oldValue, ok := from[field]
+		if !ok || !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, models.FieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	for field, oldValue := range from {
+		if _, ok := to[field]; !ok {
+			changes = append(changes, models.FieldChange{Field: field, Old: oldValue, New: nil})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+func loadRevision(q querier, productID, revision int) (models.ProductRevision, error) {
+	var rev models.ProductRevision
+	var snapshotJSON, diffJSON []byte
+
+	err := q.QueryRow(`
+		SELECT id, product_id, revision, action, actor_id, snapshot, diff, created_at
+		FROM product_revisions
+		WHERE product_id = ? AND revision = ?
+	`, productID, revision).Scan(
+		&rev.ID, &rev.ProductID, &rev.Revision, &rev.Action, &rev.ActorID,
+		&snapshotJSON, &diffJSON, &rev.CreatedAt,
+	)
+	if err != nil {
+		return rev, err
+	}
+
+	var snapshot models.ProductDetail
+	if err := json.Unmarshal(snapshotJSON, &snapshot); err != nil {
+		return rev, err
+	}
+	rev.Snapshot = &snapshot
+	if err := json.Unmarshal(diffJSON, &rev.Diff); err != nil {
+		return rev, err
+	}
+
+	return rev, nil
+}
+
+func ListProductRevisions(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("list_revisions", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int
+	err = database.DB.QueryRow(
+		"SELECT COUNT(*) FROM product_revisions WHERE product_id = ?",
+		productID,
+	).Scan(&total)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, product_id, revision, action, actor_id, diff, created_at
+		FROM product_revisions
+		WHERE product_id = ?
+		ORDER BY revision DESC
+		LIMIT ? OFFSET ?
+	`, productID, pagination.PageSize, (pagination.Page-1)*pagination.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	revisions := []models.ProductRevision{}
+	for rows.Next() {
+		var rev models.ProductRevision
+		var diffJSON []byte
+		if err := rows.Scan(
+			&rev.ID, &rev.ProductID, &rev.Revision, &rev.Action, &rev.ActorID, &diffJSON, &rev.CreatedAt,
+		); err != nil {
+			log.Printf("Error scanning revision: %v", err)
+			continue
+		}
+		if err := json.Unmarshal(diffJSON, &rev.Diff); err != nil {
+			log.Printf("Error decoding revision diff: %v", err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions":  revisions,
+		"total":      total,
+		"page":       pagination.Page,
+		"page_size":  pagination.PageSize,
+		"total_page": utils.CalculateTotalPages(total, pagination.PageSize),
+	})
+}
+
+func GetProductRevision(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("get_revision", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+
+	rev, err := loadRevision(database.DB, productID, revision)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// CompareProductRevisions 比较两个修订，to 默认为最新修订，from 默认为 to 的上一个修订
+func CompareProductRevisions(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("compare_revisions", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	to, err := strconv.Atoi(c.DefaultQuery("to", "0"))
+	if err != nil || to < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to revision"})
+		return
+	}
+	if to == 0 {
+		err = database.DB.QueryRow(
+			"SELECT COALESCE(MAX(revision), 0) FROM product_revisions WHERE product_id = ?",
+			productID,
+		).Scan(&to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+	}
+
+	from, err := strconv.Atoi(c.DefaultQuery("from", strconv.Itoa(to-1)))
+	if err != nil || from < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from revision"})
+		return
+	}
+
+	toRev, err := loadRevision(database.DB, productID, to)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// from 为0表示与空快照比较
+	fromFields := map[string]interface{}{}
+	if from > 0 {
+		fromRev, err := loadRevision(database.DB, productID, from)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		fromFields = flattenSnapshot(*fromRev.Snapshot)
+	}
+
+	c.JSON(http.StatusOK, models.RevisionDiff{
+		ProductID:    productID,
+		FromRevision: from,
+		ToRevision:   to,
+		Changes:      diffSnapshots(fromFields, flattenSnapshot(*toRev.Snapshot)),
+	})
+}
+
+// RestoreProductRevision 将商品回滚到指定修订的快照，POST /products/:id/revisions/:rev/restore
+// 恢复的字段：名称、描述、价格、库存、分类、SKU、条码、主图、重量和尺寸，以及属性、图片和客户分组价格。
+// 状态、定时发布时间、定时调价、阶梯价格和删除状态不在快照中，保持当前值。快照中的属性按当前的属性定义校验，
+// 已发布或定时发布的商品缺少分类必填属性时返回 409
+func RestoreProductRevision(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("restore_revision", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockProductStatus(tx, productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	rev, err := loadRevision(tx, productID, revision)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	snapshot := rev.Snapshot
+	// 修订中的 SKU 或条码可能已被其他商品使用
+	if respondSKUConflict(c, tx, snapshot.SKU, productID) || respondGTINConflict(c, tx, snapshot.GTIN, productID) {
+		return
+	}
+
+	// 属性定义可能在修订之后发生变化，快照中的属性须符合当前定义，已定义的属性只能出现一次
+	definitions, err := loadAttributeDefinitions(tx, snapshot.CategoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	attributes := append([]models.ProductAttribute(nil), snapshot.Attributes...)
+	defined := map[string]bool{}
+	for i := range attributes {
+		definition, err := normalizeProductAttribute(definitions, &attributes[i])
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Revision attributes do not match the current definitions: " + err.Error()})
+			return
+		}
+		if definition != nil {
+			if defined[definition.Name] {
+				c.JSON(http.StatusConflict, gin.H{"error": "Revision has attribute " + definition.Name + " more than once"})
+				return
+			}
+			defined[definition.Name] = true
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE products
+		SET name = ?, description = ?, price = ?, price_amount = ?, currency = ?, stock = ?,
+		    category_id = ?, sku = ?, gtin = ?, image_url = ?,
+		    weight = ?, weight_unit = ?, length = ?, width = ?, height = ?, dimension_unit = ?, updated_at = NOW()
+		WHERE id = ?
+	`,
+		snapshot.Name, snapshot.Description, snapshot.Price.Decimal(), snapshot.Price.Amount, snapshot.Price.Currency, snapshot.Stock,
+		snapshot.CategoryID, snapshot.SKU, snapshot.GTIN, snapshot.ImageURL,
+		snapshot.Weight.Value, snapshot.Weight.Unit, snapshot.Dimensions.Length, snapshot.Dimensions.Width, snapshot.Dimensions.Height, snapshot.Dimensions.Unit,
+		productID)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			_ = tx.Rollback()
+			respondUniqueKeyConflict(c, snapshot.SKU, snapshot.GTIN, productID)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore product"})
+		return
+	}
+
+	if err := replaceProductAttributes(tx, productID, attributes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore attributes"})
+		return
+	}
+	// 状态不随修订恢复，已发布或定时发布的商品恢复后仍须包含分类的必填属性
+	if status == models.ProductStatusPublished || status == models.ProductStatusScheduled {
+		missing, err := missingRequiredAttributes(tx, productID, snapshot.CategoryID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if len(missing) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Missing required attributes for the restored category", "missing_attributes": missing})
+			return
+		}
+	}
+	if err := replaceProductImages(tx, productID, snapshot.Images); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore images"})
+		return
+	}
+	if err := replaceProductPrices(tx, productID, snapshot.Prices); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore prices"})
+		return
+	}
+
+	newRevision, err := recordRevision(tx, productID, models.RevisionActionRestoreRevision, c.GetInt("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	if rabbitMQ != nil {
+		product := snapshot.Product
+		product.ID = productID
+		sendProductEvent(models.EventProductUpdated, productID, product)
+	}
+	reindexProducts(productID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Product restored",
+		"restored_from": revision,
+		"revision":      newRevision,
+	})
+}
+
+// replaceProductAttributes 用给定的属性集合替换商品现有属性，保留原有ID
+func replaceProductAttributes(tx *sql.Tx, productID int, attributes []models.ProductAttribute) error {
+	if _, err := tx.Exec("DELETE FROM product_attributes WHERE product_id = ?", productID); err != nil {
+		return err
+	}
+	for _, attr := range attributes {
+		_, err := tx.Exec(`
+			INSERT INTO product_attributes (id, product_id, name, value, number_value)
+			VALUES (?, ?, ?, ?, ?)
+		`, attr.ID, productID, attr.Name, attr.Value, attributeNumberValue(attr.Value))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceProductImages 用给定的图片集合替换商品现有图片，保留原有ID
+func replaceProductImages(tx *sql.Tx, productID int, images []models.ProductImage) error {
+	if _, err := tx.Exec("DELETE FROM product_images WHERE product_id = ?", productID); err != nil {
+		return err
+	}
+	for _, img := range images {
+		_, err := tx.Exec(`
+			INSERT INTO product_images (id, product_id, image_url, is_primary)
+			VALUES (?, ?, ?, ?)
+		`, img.ID, productID, img.ImageURL, img.IsPrimary)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceProductPrices 用给定的价格表替换商品现有的多币种价格
+func replaceProductPrices(tx *sql.Tx, productID int, prices []models.Money) error {
+	if _, err := tx.Exec("DELETE FROM product_prices WHERE product_id = ?", productID); err != nil {
+		return err
+	}
+	for _, price := range prices {
+		_, err := tx.Exec(`
+			INSERT INTO product_prices (product_id, currency, amount)
+			VALUES (?, ?, ?)
+		`, productID, price.Currency, price.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
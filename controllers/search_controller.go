@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"log"
+	"product-service/config"
+	"product-service/database"
+	"product-service/models"
+	"product-service/search"
+)
+
+var (
+	searchEngine     search.Engine = search.NewMySQLEngine()
+	searchMaxResults               = 1000
+)
+
+// InitSearch 根据配置选择搜索引擎，并在后台补齐索引
+func InitSearch(cfg *config.Config) {
+	switch cfg.SearchBackend {
+	case "memory":
+		searchEngine = search.NewMemoryEngine()
+	case "mysql":
+		searchEngine = search.NewMySQLEngine()
+	default:
+		log.Printf("Unknown SEARCH_BACKEND %q, using mysql", cfg.SearchBackend)
+		searchEngine = search.NewMySQLEngine()
+	}
+	if cfg.SearchMaxResults > 0 {
+		searchMaxResults = cfg.SearchMaxResults
+	}
+
+	go func() {
+		if err := buildSearchIndex(); err != nil {
+			log.Printf("Failed to build search index: %v", err)
+		}
+	}()
+}
+
+// buildSearchIndex 进程内索引每次启动全量构建，MySQL 索引只补齐缺失的商品
+func buildSearchIndex() error {
+	where := ""
+	if !searchEngine.InProcess() {
+		where = " AND NOT EXISTS (SELECT 1 FROM product_search_index s WHERE s.product_id = p.id)"
+	}
+
+	count := 0
+	err := streamProducts(where, nil, func(p *models.ProductDetail) error {
+		count++
+		return searchEngine.Index(searchDocument(p))
+	}, func() error { return nil })
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Search index built, %d products indexed", count)
+	return nil
+}
+
+// searchDocument 将商品转换为搜索文档，属性按"名称 值"的形式参与索引
+func searchDocument(p *models.ProductDetail) search.Document {
+	doc := search.Document{
+		ProductID:   p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Category:    p.CategoryName,
+		Status:      p.Status,
+	}
+	for _, attr := range p.Attributes {
+		doc.Attributes = append(doc.Attributes, attr.Name+" "+attr.Value)
+	}
+	return doc
+}
+
+// reindexProducts 重新索引指定商品（含自动补全），已删除的商品从索引中移除，失败只记录日志
+func reindexProducts(productIDs ...int) {
+	if len(productIDs) == 0 {
+		return
+	}
+
+	products, err := loadProductDetailsByID(database.DB, productIDs)
+	if err != nil {
+		log.Printf("Failed to load products %v for search index: %v", productIDs, err)
+		return
+	}
+
+	indexSuggestions(productIDs, products)
+
+	found := make(map[int]bool, len(products))
+	for i := range products {
+		found[products[i].ID] = true
+		if err := searchEngine.Index(searchDocument(&products[i])); err != nil {
+			log.Printf("Failed to index product %d: %v", products[i].ID, err)
+		}
+	}
+	for _, id := range productIDs {
+		if found[id] {
+			continue
+		}
+		if err := searchEngine.Remove(id); err != nil {
+			log.Printf("Failed to remove product %d from search index: %v", id, err)
+		}
+	}
+}
+
+// HandleSearchEvent 进程内索引根据其他副本发出的商品事件刷新
+func HandleSearchEvent(event models.ProductEvent) {
+	if !searchEngine.InProcess() {
+		return
+	}
+
+	switch event.EventType {
+	case models.EventCategoryCreated, models.EventImageAdded, models.EventImageUpdated, models.EventImageRemoved:
+		return
+	case models.EventProductsImported:
+		reindexProducts(event.ProductIDs...)
+	default:
+		if event.ProductID > 0 {
+			reindexProducts(event.ProductID)
+		}
+	}
+}
+
+// searchProductIDs 执行全文搜索，返回按相关度排序的商品ID，
+// 命中数超过 searchMaxResults 时只返回前 searchMaxResults 个并返回 truncated
+func searchProductIDs(q search.Query) (ids []int, truncated bool, err error) {
+	// 多取一条用于判断结果是否被截断
+	hits, err := searchEngine.Search(q, searchMaxResults+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(hits) > searchMaxResults {
+		hits = hits[:searchMaxResults]
+		truncated = true
+	}
+
+	ids = make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ProductID
+	}
+	return ids, truncated, nil
+}
@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"product-service/search"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchDictionary 查询时使用的同义词和停用词，各副本从数据库加载
+var searchDictionary = search.NewDictionary()
+
+// loadSearchDictionary 从数据库重新加载同义词和停用词
+func loadSearchDictionary() error {
+	synonyms, err := querySearchSynonyms()
+	if err != nil {
+		return err
+	}
+
+	rows, err := database.DB.Query("SELECT word FROM search_stop_words")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var stopWords []string
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return err
+		}
+		stopWords = append(stopWords, word)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	searchDictionary.Load(synonyms, stopWords)
+	return nil
+}
+
+// StartSearchDictionaryRefresher 启动时加载词典并定期重新加载，作为变更事件丢失时的兜底
+func StartSearchDictionaryRefresher(interval time.Duration) {
+	if err := loadSearchDictionary(); err != nil {
+		log.Printf("Failed to load search dictionary: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := loadSearchDictionary(); err != nil {
+			log.Printf("Failed to reload search dictionary: %v", err)
+		}
+	}
+}
+
+// HandleSearchDictionaryEvent 其他副本修改词典后重新加载
+func HandleSearchDictionaryEvent(event models.ProductEvent) {
+	if event.EventType != models.EventSearchDictionaryUpdated {
+		return
+	}
+	if err := loadSearchDictionary(); err != nil {
+		log.Printf("Failed to reload search dictionary: %v", err)
+	}
+}
+
+// searchDictionaryChanged 本副本立即重新加载，并通知其他副本
+func searchDictionaryChanged() {
+	if err := loadSearchDictionary(); err != nil {
+		log.Printf("Failed to reload search dictionary: %v", err)
+	}
+	sendBroadcastEvent(models.EventSearchDictionaryUpdated)
+}
+
+func querySearchSynonyms() ([]models.SearchSynonym, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, term, synonyms, bidirectional, created_at, updated_at
+		FROM search_synonyms
+		ORDER BY term
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	synonyms := []models.SearchSynonym{}
+	for rows.Next() {
+		var syn models.SearchSynonym
+		var values []byte
+		if err := rows.Scan(&syn.ID, &syn.Term, &values, &syn.Bidirectional, &syn.CreatedAt, &syn.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(values, &syn.Synonyms); err != nil {
+			return nil, err
+		}
+		synonyms = append(synonyms, syn)
+	}
+	return synonyms, rows.Err()
+}
+
+// normalizeSynonym 去掉首尾空白和重复的同义词
+func normalizeSynonym(syn *models.SearchSynonym) error {
+	syn.Term = strings.TrimSpace(syn.Term)
+	if len(search.Tokenize(syn.Term)) == 0 {
+		return errors.New("term must contain at least one word")
+	}
+
+	seen := map[string]bool{strings.ToLower(syn.Term): true}
+	var values []string
+	for _, v := range syn.Synonyms {
+		v = strings.TrimSpace(v)
+		if len(search.Tokenize(v)) == 0 || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return errors.New("synonyms must contain at least one word different from term")
+	}
+	syn.Synonyms = values
+	return nil
+}
+
+// ListSearchSynonyms 列出全部同义词
+func ListSearchSynonyms(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("list_synonyms", status)
+	}()
+
+	synonyms, err := querySearchSynonyms()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"synonyms": synonyms})
+}
+
+// CreateSearchSynonym 新增同义词
+func CreateSearchSynonym(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("create_synonym", status)
+	}()
+
+	var syn models.SearchSynonym
+	if err := c.ShouldBindJSON(&syn); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeSynonym(&syn); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	values, _ := json.Marshal(syn.Synonyms)
+	result, err := database.DB.Exec(
+		"INSERT INTO search_synonyms (term, synonyms, bidirectional) VALUES (?, ?, ?)",
+		syn.Term, values, syn.Bidirectional,
+	)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Synonym for this term already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create synonym"})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	middlewares.SetAuditEntity(c, "search_synonym", id)
+	searchDictionaryChanged()
+
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// UpdateSearchSynonym 修改同义词
+func UpdateSearchSynonym(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("update_synonym", status)
+	}()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid synonym ID"})
+		return
+	}
+
+	var syn models.SearchSynonym
+	if err := c.ShouldBindJSON(&syn); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := normalizeSynonym(&syn); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists bool
+	if err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM search_synonyms WHERE id = ?)", id).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Synonym not found"})
+		return
+	}
+
+	values, _ := json.Marshal(syn.Synonyms)
+	_, err = database.DB.Exec(
+		"UPDATE search_synonyms SET term = ?, synonyms = ?, bidirectional = ? WHERE id = ?",
+		syn.Term, values, syn.Bidirectional, id,
+	)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Synonym for this term already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update synonym"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "search_synonym", id)
+	searchDictionaryChanged()
+	c.JSON(http.StatusOK, gin.H{"message": "Synonym updated"})
+}
+
+// DeleteSearchSynonym 删除同义词
+func DeleteSearchSynonym(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("delete_synonym", status)
+	}()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid synonym ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM search_synonyms WHERE id = ?", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete synonym"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Synonym not found"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "search_synonym", id)
+	searchDictionaryChanged()
+	c.JSON(http.StatusOK, gin.H{"message": "Synonym deleted"})
+}
+
+// ListSearchStopWords 列出全部停用词
+func ListSearchStopWords(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("list_stop_words", status)
+	}()
+
+	rows, err := database.DB.Query("SELECT word, created_at FROM search_stop_words ORDER BY word")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	words := []models.SearchStopWord{}
+	for rows.Next() {
+		var w models.SearchStopWord
+		if err := rows.Scan(&w.Word, &w.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		words = append(words, w)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stop_words": words})
+}
+
+// CreateSearchStopWord 新增停用词，已存在时直接返回成功
+func CreateSearchStopWord(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("create_stop_word", status)
+	}()
+
+	var w models.SearchStopWord
+	if err := c.ShouldBindJSON(&w); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 停用词按单个词匹配
+	tokens := search.Tokenize(w.Word)
+	if len(tokens) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stop word must be a single word"})
+		return
+	}
+	w.Word = tokens[0]
+
+	if _, err := database.DB.Exec("INSERT IGNORE INTO search_stop_words (word) VALUES (?)", w.Word); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create stop word"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "search_stop_word", w.Word)
+	searchDictionaryChanged()
+	c.JSON(http.StatusCreated, gin.H{"word": w.Word})
+}
+
+// DeleteSearchStopWord 删除停用词
+func DeleteSearchStopWord(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("delete_stop_word", status)
+	}()
+
+	word := strings.ToLower(strings.TrimSpace(c.Param("word")))
+	result, err := database.DB.Exec("DELETE FROM search_stop_words WHERE word = ?", word)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete stop word"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stop word not found"})
+		return
+	}
+
+	middlewares.SetAuditEntity(c, "search_stop_word", word)
+	searchDictionaryChanged()
+	c.JSON(http.StatusOK, gin.H{"message": "Stop word deleted"})
+}
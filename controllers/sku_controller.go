@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"product-service/config"
+	"product-service/database"
+	"product-service/middlewares"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// skuFormat SKU 的格式
+var skuFormat *regexp.Regexp
+
+// skuAutoGenerate 新建商品未指定 SKU 时是否自动生成，skuGeneratePattern 为生成模板
+var (
+	skuAutoGenerate    bool
+	skuGeneratePattern string
+)
+
+// skuPatternToken 生成模板中的占位符，如 {prefix}、{seq:6}
+var skuPatternToken = regexp.MustCompile(`\{([a-z_]+)(?::(\d+))?\}`)
+
+// categorySKUPrefixFormat 分类的 SKU 前缀格式
+var categorySKUPrefixFormat = regexp.MustCompile(`^[A-Z0-9]{1,16}$`)
+
+// 生成的 SKU 与已有 SKU 冲突时的最大重试次数
+const maxSKUGenerateAttempts = 10
+
+// InitSKU 加载 SKU 格式校验和自动生成配置，模板生成的 SKU 不符合格式时返回错误
+func InitSKU(cfg *config.Config) error {
+	format, err := regexp.Compile(cfg.SKUPattern)
+	if err != nil {
+		return fmt.Errorf("invalid SKU_PATTERN: %w", err)
+	}
+	skuFormat = format
+
+	if cfg.SKUAutoGenerate {
+		for _, match := range skuPatternToken.FindAllStringSubmatch(cfg.SKUGeneratePattern, -1) {
+			switch match[1] {
+			case "prefix", "category_id", "seq":
+			default:
+				return fmt.Errorf("unknown SKU_GENERATE_PATTERN placeholder %q", match[0])
+			}
+		}
+		if !strings.Contains(cfg.SKUGeneratePattern, "{seq") {
+			return errors.New("SKU_GENERATE_PATTERN must contain {seq}")
+		}
+		if sample := renderSKUPattern(cfg.SKUGeneratePattern, "C1", 1, 1); !format.MatchString(sample) {
+			return fmt.Errorf("SKU_GENERATE_PATTERN produces %q, which does not match SKU_PATTERN", sample)
+		}
+	}
+
+	skuAutoGenerate = cfg.SKUAutoGenerate
+	skuGeneratePattern = cfg.SKUGeneratePattern
+	return nil
+}
+
+// validateSKU 去掉首尾空白并校验格式，空 SKU 不校验
+func validateSKU(sku *string) error {
+	*sku = strings.TrimSpace(*sku)
+	if *sku == "" || skuFormat == nil {
+		return nil
+	}
+	if !skuFormat.MatchString(*sku) {
+		return fmt.Errorf("sku %q does not match the required format %s", *sku, skuFormat.String())
+	}
+	return nil
+}
+
+// validateChangedSKU 只在 SKU 与商品当前的 SKU 不同时校验格式，
+// 格式规则收紧前已有的 SKU 保持不变时仍可编辑商品
+func validateChangedSKU(sku *string, current string) error {
+	*sku = strings.TrimSpace(*sku)
+	if *sku == current {
+		return nil
+	}
+	return validateSKU(sku)
+}
+
+// normalizeCategorySKUPrefix 校验分类的 SKU 前缀，统一为大写
+func normalizeCategorySKUPrefix(prefix string) (string, error) {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return "", nil
+	}
+	if !categorySKUPrefixFormat.MatchString(prefix) {
+		return "", errors.New("sku_prefix must be 1-16 letters or digits")
+	}
+	return prefix, nil
+}
+
+// findSKUConflict 查找使用该 SKU 的其他未删除商品，没有时返回 0
+func findSKUConflict(q querier, sku string, excludeID int) (int, error) {
+	if sku == "" {
+		return 0, nil
+	}
+	var id int
+	err := q.QueryRow(
+		"SELECT id FROM products WHERE sku = ? AND deleted_at IS NULL AND id <> ? ORDER BY id LIMIT 1",
+		sku, excludeID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// respondSKUConflict 检查 SKU 是否被其他商品使用，冲突时返回 409 及冲突的商品ID，已写入响应时返回 true
+func respondSKUConflict(c *gin.Context, q querier, sku string, excludeID int) bool {
+	conflictID, err := findSKUConflict(q, sku, excludeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return true
+	}
+	if conflictID > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "SKU already exists", "conflicting_product_id": conflictID})
+		return true
+	}
+	return false
+}
+
+// respondUniqueKeyConflict 写入被 SKU 或条码的唯一索引拒绝时返回 409，并发写入的冲突商品已提交时附带其ID
+func respondUniqueKeyConflict(c *gin.Context, sku, gtin string, excludeID int) {
+	if respondSKUConflict(c, database.DB, sku, excludeID) || respondGTINConflict(c, database.DB, gtin, excludeID) {
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "SKU or GTIN already exists"})
+}
+
+// renderSKUPattern 按模板生成 SKU，{seq:N} 的序号补零到 N 位
+func renderSKUPattern(pattern, prefix string, categoryID int, seq int64) string {
+	return skuPatternToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		match := skuPatternToken.FindStringSubmatch(token)
+		switch match[1] {
+		case "prefix":
+			return prefix
+		case "category_id":
+			return strconv.Itoa(categoryID)
+		case "seq":
+			width, _ := strconv.Atoi(match[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		}
+		return token
+	})
+}
+
+// generateSKU 在事务内为分类下的新商品生成 SKU。前缀取分类的 sku_prefix，未设置时为 C+分类ID；
+// 序号按前缀递增，生成的 SKU 已被使用时继续取下一个序号
+func generateSKU(tx *sql.Tx, categoryID int) (string, error) {
+	var prefix string
+	if err := tx.QueryRow("SELECT sku_prefix FROM categories WHERE id = ?", categoryID).Scan(&prefix); err != nil {
+		return "", err
+	}
+	if prefix == "" {
+		prefix = "C" + strconv.Itoa(categoryID)
+	}
+
+	for attempt := 0; attempt < maxSKUGenerateAttempts; attempt++ {
+		_, err := tx.Exec(`
+			INSERT INTO sku_sequences (prefix, last_value) VALUES (?, 1)
+			ON DUPLICATE KEY UPDATE last_value = last_value + 1
+		`, prefix)
+		if err != nil {
+			return "", err
+		}
+		var seq int64
+		if err := tx.QueryRow("SELECT last_value FROM sku_sequences WHERE prefix = ?", prefix).Scan(&seq); err != nil {
+			return "", err
+		}
+
+		sku := renderSKUPattern(skuGeneratePattern, prefix, categoryID, seq)
+		conflictID, err := findSKUConflict(tx, sku, 0)
+		if err != nil {
+			return "", err
+		}
+		if conflictID == 0 {
+			return sku, nil
+		}
+	}
+	return "", errors.New("could not generate a unique SKU")
+}
+
+// GetProductBySKU 按 SKU 获取商品，GET /products/sku/:sku
+func GetProductBySKU(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("get_by_sku", status)
+	}()
+
+	sku := strings.TrimSpace(c.Param("sku"))
+	var productID int
+	err := database.DB.QueryRow(
+		"SELECT id FROM products WHERE sku = ? AND deleted_at IS NULL ORDER BY id LIMIT 1", sku,
+	).Scan(&productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	renderProductDetail(c, productID)
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"product-service/search"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+var suggester = search.NewSuggester()
+
+// InitSuggestions 在后台构建自动补全索引
+func InitSuggestions() {
+	go func() {
+		count := 0
+		// 自动补全对公开访问，只索引已发布的商品
+		err := streamProducts(" AND p.status = ?", []interface{}{models.ProductStatusPublished}, func(p *models.ProductDetail) error {
+			suggester.Index(p.ID, p.Name, p.CategoryName, p.Attributes)
+			count++
+			return nil
+		}, func() error { return nil })
+		if err != nil {
+			log.Printf("Failed to build suggestion index: %v", err)
+			return
+		}
+		log.Printf("Suggestion index built, %d products indexed", count)
+	}()
+}
+
+// indexSuggestions 用已加载的商品刷新自动补全索引，productIDs 中未加载到或未发布的商品从索引中移除
+func indexSuggestions(productIDs []int, products []models.ProductDetail) {
+	found := make(map[int]bool, len(products))
+	for i := range products {
+		if products[i].Status != models.ProductStatusPublished {
+			continue
+		}
+		found[products[i].ID] = true
+		suggester.Index(products[i].ID, products[i].Name, products[i].CategoryName, products[i].Attributes)
+	}
+	for _, id := range productIDs {
+		if !found[id] {
+			suggester.Remove(id)
+		}
+	}
+}
+
+// HandleSuggestEvent 根据商品事件刷新自动补全索引
+func HandleSuggestEvent(event models.ProductEvent) {
+	var ids []int
+	switch event.EventType {
+	case models.EventCategoryCreated, models.EventImageAdded, models.EventImageUpdated, models.EventImageRemoved:
+		return
+	case models.EventProductsImported:
+		ids = event.ProductIDs
+	default:
+		if event.ProductID > 0 {
+			ids = []int{event.ProductID}
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	products, err := loadProductDetailsByID(database.DB, ids)
+	if err != nil {
+		log.Printf("Failed to refresh suggestions for products %v: %v", ids, err)
+		return
+	}
+	indexSuggestions(ids, products)
+}
+
+// SuggestProducts 搜索框自动补全，返回商品名称、分类和属性值建议
+func SuggestProducts(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("suggest", status)
+	}()
+
+	limit := defaultSuggestLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSuggestLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer between 1 and 50"})
+			return
+		}
+		limit = n
+	}
+
+	suggestions := suggester.Suggest(c.Query("q"), limit)
+	if suggestions == nil {
+		suggestions = []models.Suggestion{}
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
@@ -0,0 +1,281 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"product-service/config"
+	"product-service/database"
+	"product-service/middlewares"
+	"product-service/models"
+	"product-service/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lockDeletedProduct 在事务内锁定已软删除的商品行，商品不在回收站时返回 sql.ErrNoRows
+func lockDeletedProduct(tx *sql.Tx, productID int) error {
+	var id int
+	return tx.QueryRow(
+		"SELECT id FROM products WHERE id = ? AND deleted_at IS NOT NULL FOR UPDATE",
+		productID,
+	).Scan(&id)
+}
+
+// ListDeletedProducts 列出回收站中的商品
+func ListDeletedProducts(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("list_trash", status)
+	}()
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int
+	err = database.DB.QueryRow(
+		"SELECT COUNT(*) FROM products WHERE deleted_at IS NOT NULL",
+	).Scan(&total)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT `+productDetailColumns+`, p.deleted_at
+		FROM products p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.deleted_at IS NOT NULL
+		ORDER BY p.deleted_at DESC, p.id DESC
+		LIMIT ? OFFSET ?
+	`, pagination.PageSize, (pagination.Page-1)*pagination.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	products := []models.DeletedProduct{}
+	for rows.Next() {
+		var p models.DeletedProduct
+		if err := rows.Scan(append(productDetailDest(&p.ProductDetail), &p.DeletedAt)...); err != nil {
+			log.Printf("Error scanning deleted product: %v", err)
+			continue
+		}
+		products = append(products, p)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products":   products,
+		"total":      total,
+		"page":       pagination.Page,
+		"page_size":  pagination.PageSize,
+		"total_page": utils.CalculateTotalPages(total, pagination.PageSize),
+	})
+}
+
+// RestoreDeletedProduct 将回收站中的商品恢复为正常状态
+func RestoreDeletedProduct(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("restore", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	// 开始事务
+	tx, err := database.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockDeletedProduct(tx, productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deleted product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 删除期间 SKU 或条码可能已被其他商品使用
+	var sku, gtin string
+	if err := tx.QueryRow("SELECT sku, gtin FROM products WHERE id = ?", productID).Scan(&sku, &gtin); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if respondSKUConflict(c, tx, sku, productID) || respondGTINConflict(c, tx, gtin, productID) {
+		return
+	}
+
+	if err := ensureBaselineRevision(tx, productID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE products SET deleted_at = NULL, updated_at = NOW() WHERE id = ?", productID)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			_ = tx.Rollback()
+			respondUniqueKeyConflict(c, sku, gtin, productID)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore product"})
+		return
+	}
+
+	if _, err := recordRevision(tx, productID, models.RevisionActionUndelete, c.GetInt("userID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	product, err := loadProductDetail(tx, productID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return
+	}
+
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventProductRestored, productID, product.Product)
+	}
+	reindexProducts(productID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product restored"})
+}
+
+// PurgeDeletedProduct 永久删除回收站中的商品及其属性、图片和修订历史
+func PurgeDeletedProduct(c *gin.Context) {
+	defer func() {
+		status := c.Writer.Status() >= 200 && c.Writer.Status() < 300
+		middlewares.RecordProductOperation("purge", status)
+	}()
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := purgeProduct(productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deleted product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge product"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product purged"})
+}
+
+// purgeProduct 在单独事务中永久删除一个已软删除的商品
+func purgeProduct(productID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockDeletedProduct(tx, productID); err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		"DELETE FROM product_attributes WHERE product_id = ?",
+		"DELETE FROM product_images WHERE product_id = ?",
+		"DELETE FROM product_prices WHERE product_id = ?",
+		"DELETE FROM product_price_tiers WHERE product_id = ?",
+		"DELETE FROM price_schedules WHERE product_id = ?",
+		"DELETE FROM product_revisions WHERE product_id = ?",
+		"DELETE FROM products WHERE id = ?",
+	} {
+		if _, err := tx.Exec(stmt, productID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if rabbitMQ != nil {
+		sendProductEvent(models.EventProductPurged, productID, nil)
+	}
+	reindexProducts(productID)
+	return nil
+}
+
+// StartTrashRetentionJob 定期永久删除超过保留期的软删除商品
+func StartTrashRetentionJob(cfg *config.Config) {
+	if cfg.TrashRetentionDays <= 0 {
+		log.Println("Trash retention job disabled")
+		return
+	}
+
+	ticker := time.NewTicker(cfg.TrashPurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purgeExpiredProducts(cfg.TrashRetentionDays)
+	}
+}
+
+func purgeExpiredProducts(retentionDays int) {
+	for {
+		rows, err := database.DB.Query(`
+			SELECT id FROM products
+			WHERE deleted_at IS NOT NULL AND deleted_at < DATE_SUB(NOW(), INTERVAL ? DAY)
+			ORDER BY id
+			LIMIT 100
+		`, retentionDays)
+		if err != nil {
+			log.Printf("Failed to query expired products: %v", err)
+			return
+		}
+
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		rows.Close()
+
+		if len(ids) == 0 {
+			return
+		}
+
+		purged := 0
+		for _, id := range ids {
+			// 其他副本可能已经清理或恢复了该商品
+			if err := purgeProduct(id); err != nil {
+				if !errors.Is(err, sql.ErrNoRows) {
+					log.Printf("Failed to purge product %d: %v", id, err)
+				}
+				continue
+			}
+			purged++
+		}
+		log.Printf("Purged %d products deleted more than %d days ago", purged, retentionDays)
+
+		if purged == 0 {
+			return
+		}
+	}
+}
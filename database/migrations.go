@@ -0,0 +1,374 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// columnMigration 为已有表补充字段
+type columnMigration struct {
+	Table      string
+	Column     string
+	Definition string
+}
+
+// triggerMigration 数据库层面的约束触发器
+type triggerMigration struct {
+	Name       string
+	Definition string
+}
+
+// indexMigration 为已有表补充索引
+type indexMigration struct {
+	Table      string
+	Name       string
+	Definition string
+}
+
+// schemaTables 服务依赖的新增表
+var schemaTables = []string{
+	`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id INT NOT NULL,
+		idempotency_key VARCHAR(255) NOT NULL,
+		request_hash CHAR(64) NOT NULL,
+		status_code INT NOT NULL DEFAULT 0,
+		content_type VARCHAR(255) NOT NULL DEFAULT '',
+		response_body MEDIUMBLOB,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL,
+		UNIQUE KEY uk_idempotency_user_key (user_id, idempotency_key),
+		KEY idx_idempotency_expires (expires_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS product_revisions (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		product_id INT NOT NULL,
+		revision INT NOT NULL,
+		action VARCHAR(32) NOT NULL,
+		actor_id INT NOT NULL DEFAULT 0,
+		snapshot JSON NOT NULL,
+		diff JSON NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uk_product_revision (product_id, revision)
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_logs (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		occurred_at DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+		user_id INT NOT NULL DEFAULT 0,
+		api_key_fingerprint VARCHAR(64) NOT NULL DEFAULT '',
+		service_identity VARCHAR(128) NOT NULL DEFAULT '',
+		client_ip VARCHAR(64) NOT NULL DEFAULT '',
+		request_id VARCHAR(128) NOT NULL DEFAULT '',
+		method VARCHAR(10) NOT NULL,
+		route VARCHAR(255) NOT NULL DEFAULT '',
+		path VARCHAR(1024) NOT NULL DEFAULT '',
+		entity_type VARCHAR(64) NOT NULL DEFAULT '',
+		entity_id VARCHAR(64) NOT NULL DEFAULT '',
+		status_code INT NOT NULL,
+		outcome VARCHAR(16) NOT NULL,
+		latency_ms BIGINT NOT NULL DEFAULT 0,
+		KEY idx_audit_occurred (occurred_at),
+		KEY idx_audit_user (user_id, occurred_at),
+		KEY idx_audit_entity (entity_type, entity_id),
+		KEY idx_audit_request (request_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS import_jobs (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		status VARCHAR(16) NOT NULL,
+		format VARCHAR(16) NOT NULL,
+		event_mode VARCHAR(16) NOT NULL,
+		total_rows INT NOT NULL DEFAULT 0,
+		processed_rows INT NOT NULL DEFAULT 0,
+		created_count INT NOT NULL DEFAULT 0,
+		updated_count INT NOT NULL DEFAULT 0,
+		failed_count INT NOT NULL DEFAULT 0,
+		created_by INT NOT NULL DEFAULT 0,
+		error VARCHAR(1000) NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		started_at DATETIME NULL,
+		finished_at DATETIME NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS import_job_errors (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		job_id INT NOT NULL,
+		row_no INT NOT NULL,
+		sku VARCHAR(255) NOT NULL DEFAULT '',
+		error VARCHAR(1000) NOT NULL,
+		KEY idx_import_errors_job (job_id, row_no)
+	)`,
+	// 全文搜索索引，每个字段单独建立 FULLTEXT 以便分别计算相关度
+	`CREATE TABLE IF NOT EXISTS product_search_index (
+		product_id INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		description TEXT,
+		attributes TEXT,
+		category VARCHAR(255) NOT NULL DEFAULT '',
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		FULLTEXT KEY ft_search_all (name, description, attributes, category),
+		FULLTEXT KEY ft_search_name (name),
+		FULLTEXT KEY ft_search_description (description),
+		FULLTEXT KEY ft_search_attributes (attributes),
+		FULLTEXT KEY ft_search_category (category)
+	) ENGINE=InnoDB`,
+	`CREATE TABLE IF NOT EXISTS search_synonyms (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		term VARCHAR(255) NOT NULL,
+		synonyms JSON NOT NULL,
+		bidirectional BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uk_search_synonyms_term (term)
+	)`,
+	`CREATE TABLE IF NOT EXISTS search_stop_words (
+		word VARCHAR(100) PRIMARY KEY,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	// 商品在基础币种以外的价格，金额为最小货币单位
+	`CREATE TABLE IF NOT EXISTS product_prices (
+		product_id INT NOT NULL,
+		currency CHAR(3) NOT NULL,
+		amount BIGINT NOT NULL,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		PRIMARY KEY (product_id, currency),
+		KEY idx_product_prices_currency (currency, amount)
+	)`,
+	// 汇率：1 单位 base 币种可兑换的 currency 数量
+	`CREATE TABLE IF NOT EXISTS exchange_rates (
+		base CHAR(3) NOT NULL,
+		currency CHAR(3) NOT NULL,
+		rate DECIMAL(30,15) NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (base, currency)
+	)`,
+	// 计划价格，previous_amount 为生效前的价格，用于结束时恢复
+	`CREATE TABLE IF NOT EXISTS price_schedules (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		product_id INT NOT NULL,
+		currency CHAR(3) NOT NULL,
+		amount BIGINT NOT NULL,
+		starts_at DATETIME NOT NULL,
+		ends_at DATETIME NULL,
+		status VARCHAR(16) NOT NULL,
+		previous_amount BIGINT NULL,
+		created_by INT NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		activated_at DATETIME NULL,
+		ended_at DATETIME NULL,
+		KEY idx_price_schedules_product (product_id, currency),
+		KEY idx_price_schedules_status (status, starts_at),
+		KEY idx_price_schedules_ends (status, ends_at)
+	)`,
+	// 阶梯价格，customer_group 为空表示适用于所有客户
+	`CREATE TABLE IF NOT EXISTS product_price_tiers (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		product_id INT NOT NULL,
+		customer_group VARCHAR(64) NOT NULL DEFAULT '',
+		currency CHAR(3) NOT NULL,
+		min_quantity INT NOT NULL,
+		amount BIGINT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uk_price_tiers (product_id, customer_group, currency, min_quantity)
+	)`,
+	// 目录级促销规则，percent_off 为万分比，amount_off 为最小货币单位
+	`CREATE TABLE IF NOT EXISTS promotions (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		description VARCHAR(1000) NOT NULL DEFAULT '',
+		scope JSON NOT NULL,
+		discount_type VARCHAR(20) NOT NULL,
+		percent_off INT NOT NULL DEFAULT 0,
+		amount_off BIGINT NULL,
+		amount_currency CHAR(3) NULL,
+		buy_quantity INT NOT NULL DEFAULT 0,
+		free_quantity INT NOT NULL DEFAULT 0,
+		starts_at DATETIME NULL,
+		ends_at DATETIME NULL,
+		priority INT NOT NULL DEFAULT 0,
+		stackable BOOLEAN NOT NULL DEFAULT FALSE,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		state VARCHAR(20) NOT NULL DEFAULT 'pending',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		KEY idx_promotions_state (state)
+	)`,
+	// 分类的属性定义，aliases、allowed_values 为 JSON 字符串数组
+	`CREATE TABLE IF NOT EXISTS attribute_definitions (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		category_id INT NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		type VARCHAR(20) NOT NULL,
+		aliases JSON NOT NULL,
+		allowed_values JSON NOT NULL,
+		unit VARCHAR(8) NOT NULL DEFAULT '',
+		required BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uk_attribute_definitions (category_id, name)
+	)`,
+	// 按 SKU 前缀分配的自动生成序号
+	`CREATE TABLE IF NOT EXISTS sku_sequences (
+		prefix VARCHAR(64) PRIMARY KEY,
+		last_value BIGINT NOT NULL
+	)`,
+}
+
+// schemaTriggers 数据库层面的约束触发器，需要 TRIGGER 权限，创建失败时迁移失败
+var schemaTriggers = []triggerMigration{
+	// 审计日志只允许追加
+	{"audit_logs_no_update", `BEFORE UPDATE ON audit_logs
+		FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only'`},
+	{"audit_logs_no_delete", `BEFORE DELETE ON audit_logs
+		FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only'`},
+}
+
+// schemaColumns 已有表需要补充的字段
+var schemaColumns = []columnMigration{
+	// 价格以最小货币单位保存，原 price 列保留并同步写入以兼容旧的读取方
+	{"products", "price_amount", "BIGINT NOT NULL DEFAULT 0"},
+	{"products", "currency", "CHAR(3) NOT NULL DEFAULT '{currency}'"},
+	// 商品生命周期状态，已有商品视为已发布
+	{"products", "status", "VARCHAR(20) NOT NULL DEFAULT 'published'"},
+	{"products", "publish_at", "DATETIME NULL"},
+	{"products", "published_at", "DATETIME NULL"},
+	// 未删除且非空的 SKU 唯一，软删除的商品不占用 SKU
+	{"products", "sku_key", "VARCHAR(255) AS (IF(deleted_at IS NULL AND sku <> '', sku, NULL)) STORED"},
+	// 商品条码，保存为 GTIN-14，唯一性规则与 SKU 相同
+	{"products", "gtin", "VARCHAR(14) NOT NULL DEFAULT ''"},
+	{"products", "gtin_key", "VARCHAR(14) AS (IF(deleted_at IS NULL AND gtin <> '', gtin, NULL)) STORED"},
+	// 包装重量和尺寸，按录入时的单位保存
+	{"products", "weight", "DOUBLE NULL"},
+	{"products", "weight_unit", "VARCHAR(8) NOT NULL DEFAULT ''"},
+	{"products", "length", "DOUBLE NULL"},
+	{"products", "width", "DOUBLE NULL"},
+	{"products", "height", "DOUBLE NULL"},
+	{"products", "dimension_unit", "VARCHAR(8) NOT NULL DEFAULT ''"},
+	// 数值属性的值，用于范围过滤
+	{"product_attributes", "number_value", "DOUBLE NULL"},
+	// 分类的 SKU 前缀，用于自动生成 SKU
+	{"categories", "sku_prefix", "VARCHAR(32) NOT NULL DEFAULT ''"},
+	// 导入任务的心跳时间，用于识别服务重启后中断的任务
+	{"import_jobs", "heartbeat_at", "DATETIME NULL"},
+	// 幂等键占用者的随机令牌，释放和记录响应时只操作自己的占用
+	{"idempotency_keys", "token", "CHAR(32) NOT NULL DEFAULT ''"},
+}
+
+// dataMigrations 幂等的数据回填语句，在补充字段之后执行
+var dataMigrations = []string{
+	// 历史价格均为默认币种，按默认币种的小数位数换算为最小货币单位
+	`UPDATE products SET price_amount = ROUND(price * {minor_unit_scale}) WHERE price_amount = 0 AND price > 0`,
+	// 已有商品的发布时间取创建时间
+	`UPDATE products SET published_at = created_at WHERE status = 'published' AND published_at IS NULL`,
+	// 纯数字的属性值补齐数值列，带单位的属性值由属性迁移接口补齐
+	`UPDATE product_attributes SET number_value = CAST(value AS DOUBLE)
+		WHERE number_value IS NULL AND value REGEXP '^-?[0-9]+(\\.[0-9]+)?$'`,
+}
+
+// schemaIndexes 已有表需要补充的索引，创建失败只记录日志（可能存在历史脏数据）
+var schemaIndexes = []indexMigration{
+	{"products", "idx_products_currency_price", "INDEX idx_products_currency_price (currency, price_amount)"},
+	{"products", "idx_products_status_publish_at", "INDEX idx_products_status_publish_at (status, publish_at)"},
+	{"products", "uk_products_sku_key", "UNIQUE INDEX uk_products_sku_key (sku_key)"},
+	{"products", "uk_products_gtin_key", "UNIQUE INDEX uk_products_gtin_key (gtin_key)"},
+	{"product_attributes", "idx_product_attributes_number", "INDEX idx_product_attributes_number (name, number_value)"},
+}
+
+// Migrate 确保数据库结构与当前版本一致。currency 为默认币种，exponent 为其小数位数，
+// 用于替换字段定义和回填语句中的 {currency}、{minor_unit_scale}
+func Migrate(currency string, exponent int) error {
+	placeholders := strings.NewReplacer(
+		"{currency}", currency,
+		"{minor_unit_scale}", strconv.FormatFloat(math.Pow10(exponent), 'f', -1, 64),
+	)
+
+	for _, stmt := range schemaTables {
+		if _, err := DB.Exec(stmt); err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+	}
+
+	for _, col := range schemaColumns {
+		exists, err := columnExists(col.Table, col.Column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.Table, col.Column, placeholders.Replace(col.Definition))
+		if _, err := DB.Exec(stmt); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", col.Table, col.Column, err)
+		}
+	}
+
+	for _, stmt := range dataMigrations {
+		if _, err := DB.Exec(placeholders.Replace(stmt)); err != nil {
+			return fmt.Errorf("data migration: %w", err)
+		}
+	}
+
+	for _, idx := range schemaIndexes {
+		exists, err := indexExists(idx.Table, idx.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD %s", idx.Table, idx.Definition)
+		if _, err := DB.Exec(stmt); err != nil {
+			log.Printf("Failed to create index %s on %s: %v", idx.Name, idx.Table, err)
+		}
+	}
+
+	// 不使用 CREATE TRIGGER IF NOT EXISTS，MySQL 8.0.29 之前不支持该语法
+	for _, trigger := range schemaTriggers {
+		exists, err := triggerExists(trigger.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := DB.Exec(fmt.Sprintf("CREATE TRIGGER %s %s", trigger.Name, trigger.Definition)); err != nil {
+			return fmt.Errorf("create trigger %s: %w", trigger.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func columnExists(table, column string) (bool, error) {
+	var exists bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+		)
+	`, table, column).Scan(&exists)
+	return exists, err
+}
+
+func triggerExists(name string) (bool, error) {
+	var exists bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM information_schema.triggers
+			WHERE trigger_schema = DATABASE() AND trigger_name = ?
+		)
+	`, name).Scan(&exists)
+	return exists, err
+}
+
+func indexExists(table, index string) (bool, error) {
+	var exists bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?
+		)
+	`, table, index).Scan(&exists)
+	return exists, err
+}
@@ -8,7 +8,9 @@ import (
 	"product-service/controllers"
 	"product-service/database"
 	"product-service/middlewares"
+	"product-service/models"
 	"product-service/rabbitmq"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -24,6 +26,39 @@ func main() {
 	// 加载配置
 	cfg := config.LoadConfig()
 
+	// 设置基础币种
+	currency, err := models.NormalizeCurrency(cfg.DefaultCurrency)
+	if err != nil {
+		log.Fatalf("Invalid DEFAULT_CURRENCY: %v", err)
+	}
+	models.DefaultCurrency = currency
+
+	// 同步数据库结构，历史价格按基础币种回填
+	exponent, _ := models.CurrencyExponent(currency)
+	if err := database.Migrate(currency, exponent); err != nil {
+		log.Fatalf("Database migration failed: %v", err)
+	}
+
+	// 初始化商品 Feed
+	controllers.InitProductFeed(cfg)
+	go controllers.StartFeedRefresher(cfg.FeedRefreshInterval)
+
+	// 初始化全文搜索
+	controllers.InitSearch(cfg)
+	controllers.InitSuggestions()
+	go controllers.StartSearchDictionaryRefresher(cfg.SearchDictionaryRefresh)
+	controllers.InitProductFacets(cfg)
+	controllers.InitBatchGet(cfg)
+
+	// 初始化 SKU 格式校验和自动生成
+	if err := controllers.InitSKU(cfg); err != nil {
+		log.Fatalf("Invalid SKU configuration: %v", err)
+	}
+
+	// 初始化汇率换算
+	controllers.InitExchangeRates(cfg)
+	go controllers.StartExchangeRateRefresher(cfg.ExchangeRateRefresh)
+
 	// 初始化RabbitMQ
 	rmq, err := rabbitmq.NewRabbitMQ(cfg)
 	if err != nil {
@@ -40,10 +75,44 @@ func main() {
 			log.Println("RabbitMQ integration enabled")
 
 			// 启动消息消费者
+			consumers.SetRatesHandler(controllers.ApplyRatesUpdate)
 			go consumers.StartProductConsumer(rmq.Channel, cfg)
+
+			// 每个副本独立订阅商品事件，刷新进程内缓存
+			broadcastCh, broadcastQueue, err := rmq.DeclareBroadcastQueue()
+			if err != nil {
+				log.Printf("Failed to declare broadcast queue: %v", err)
+			} else {
+				defer broadcastCh.Close()
+				consumers.RegisterEventHandler(controllers.HandleFeedEvent)
+				consumers.RegisterEventHandler(controllers.HandleSearchEvent)
+				consumers.RegisterEventHandler(controllers.HandleSuggestEvent)
+				consumers.RegisterEventHandler(controllers.HandleSearchDictionaryEvent)
+				consumers.RegisterEventHandler(controllers.HandleExchangeRateEvent)
+				consumers.RegisterEventHandler(controllers.HandlePromotionEvent)
+				consumers.StartBroadcastConsumer(broadcastCh, broadcastQueue)
+			}
 		}
 	}
 
+	// 定期清理过期的幂等键
+	go middlewares.StartIdempotencyCleanup(time.Hour)
+
+	// 定期使到期的价格计划生效或结束
+	go controllers.StartPriceScheduler(cfg.PriceScheduleInterval)
+
+	// 定期发布到达发布时间的定时商品
+	go controllers.StartProductPublisher(cfg.ProductPublishInterval)
+
+	// 加载促销规则，定期切换促销状态
+	go controllers.StartPromotionScheduler(cfg.PromotionCheckInterval)
+
+	// 将服务重启等原因中断的导入任务标记为失败
+	go controllers.StartStaleImportJobMonitor()
+
+	// 定期永久删除超过保留期的软删除商品
+	go controllers.StartTrashRetentionJob(cfg)
+
 	// 创建Gin路由
 	r := gin.Default()
 
@@ -63,25 +132,112 @@ func main() {
 	// 公共路由
 	public := r.Group("/api")
 	{
-		public.GET("/products", controllers.ListProducts)
-		public.GET("/products/:id", controllers.GetProduct)
+		// 管理员可以查看草稿等未发布的商品，其他调用方只能看到已发布的商品
+		public.GET("/products", middlewares.OptionalAuthMiddleware(), controllers.ListProducts)
+		public.GET("/products/suggest", controllers.SuggestProducts)
+		public.POST("/products/batch", middlewares.OptionalAuthMiddleware(), controllers.GetProductsBatch)
+		public.GET("/products/sku/:sku", middlewares.OptionalAuthMiddleware(), controllers.GetProductBySKU)
+		public.GET("/products/barcode/:code", middlewares.OptionalAuthMiddleware(), controllers.GetProductByBarcode)
+		public.GET("/products/:id", middlewares.OptionalAuthMiddleware(), controllers.GetProduct)
+		public.GET("/products/:id/price-history", middlewares.OptionalAuthMiddleware(), controllers.GetProductPriceHistory)
+		// 报价按调用方令牌中的客户分组计算，未携带令牌时按普通客户计算
+		public.GET("/products/:id/quote", middlewares.OptionalAuthMiddleware(), controllers.QuoteProductPrice)
+
+		// 分类的属性定义
+		public.GET("/categories/:id/attributes", controllers.ListAttributeDefinitions)
+
+		// 商品推广 Feed
+		public.GET("/feeds/google.xml", controllers.GetGoogleFeedXML)
+		public.GET("/feeds/google.tsv", controllers.GetGoogleFeedTSV)
 	}
 
 	// 需要认证的路由组
 	authGroup := r.Group("/api")
-	authGroup.Use(middlewares.AuthMiddleware())
+	authGroup.Use(middlewares.AuditMiddleware(), middlewares.AuthMiddleware())
 	{
 		// 分类管理
-		authGroup.POST("/categories", controllers.CreateCategory)
+		authGroup.POST("/categories", middlewares.IdempotencyMiddleware(), controllers.CreateCategory)
 
 		// 商品管理
-		authGroup.POST("/products", controllers.CreateProduct)
+		authGroup.POST("/products", middlewares.IdempotencyMiddleware(), controllers.CreateProduct)
 		authGroup.PUT("/products/:id", controllers.UpdateProduct)
 		authGroup.DELETE("/products/:id", controllers.DeleteProduct)
+		authGroup.PUT("/products/:id/status", controllers.SetProductStatus)
+
+		// 商品导出
+		authGroup.GET("/products/export", controllers.ExportProducts)
+
+		// 商品批量导入
+		authGroup.POST("/products/import", controllers.ImportProducts)
+		authGroup.GET("/products/import/:jobId", controllers.GetImportJob)
+		authGroup.GET("/products/import/:jobId/errors", controllers.ListImportJobErrors)
 
 		// 商品属性管理
 		authGroup.POST("/products/:id/images", controllers.AddProductImage)
+		authGroup.PUT("/products/:id/images/:imageId", controllers.UpdateProductImage)
+		authGroup.DELETE("/products/:id/images/:imageId", controllers.DeleteProductImage)
 		authGroup.POST("/products/:id/attributes", controllers.AddProductAttribute)
+		authGroup.PUT("/products/:id/attributes", controllers.ReplaceProductAttributes)
+		authGroup.PUT("/products/:id/attributes/:attrId", controllers.UpdateProductAttribute)
+		authGroup.DELETE("/products/:id/attributes/:attrId", controllers.DeleteProductAttribute)
+
+		// 商品多币种价格
+		authGroup.PUT("/products/:id/prices/:currency", controllers.SetProductPrice)
+		authGroup.DELETE("/products/:id/prices/:currency", controllers.DeleteProductPrice)
+
+		// 商品价格计划
+		authGroup.GET("/products/:id/price-schedules", controllers.ListPriceSchedules)
+		authGroup.POST("/products/:id/price-schedules", controllers.CreatePriceSchedule)
+		authGroup.DELETE("/products/:id/price-schedules/:scheduleId", controllers.CancelPriceSchedule)
+
+		// 商品阶梯价格和客户分组价格
+		authGroup.GET("/products/:id/price-tiers", controllers.ListPriceTiers)
+		authGroup.PUT("/products/:id/price-tiers", controllers.ReplacePriceTiers)
+
+		// 商品修订历史
+		authGroup.GET("/products/:id/revisions", controllers.ListProductRevisions)
+		authGroup.GET("/products/:id/revisions/compare", controllers.CompareProductRevisions)
+		authGroup.GET("/products/:id/revisions/:rev", controllers.GetProductRevision)
+		authGroup.POST("/products/:id/revisions/:rev/restore", controllers.RestoreProductRevision)
+
+		// 审计日志（仅管理员）
+		authGroup.GET("/audit", middlewares.AdminMiddleware(), controllers.ListAuditLogs)
+	}
+
+	// 管理员路由组
+	adminGroup := authGroup.Group("/admin")
+	adminGroup.Use(middlewares.AdminMiddleware())
+	{
+		// 商品回收站
+		adminGroup.GET("/products/trash", controllers.ListDeletedProducts)
+		adminGroup.POST("/products/:id/restore", controllers.RestoreDeletedProduct)
+		adminGroup.DELETE("/products/:id/purge", controllers.PurgeDeletedProduct)
+
+		// 搜索同义词和停用词
+		adminGroup.GET("/search/synonyms", controllers.ListSearchSynonyms)
+		adminGroup.POST("/search/synonyms", controllers.CreateSearchSynonym)
+		adminGroup.PUT("/search/synonyms/:id", controllers.UpdateSearchSynonym)
+		adminGroup.DELETE("/search/synonyms/:id", controllers.DeleteSearchSynonym)
+		adminGroup.GET("/search/stop-words", controllers.ListSearchStopWords)
+		adminGroup.POST("/search/stop-words", controllers.CreateSearchStopWord)
+		adminGroup.DELETE("/search/stop-words/:word", controllers.DeleteSearchStopWord)
+
+		// 汇率
+		adminGroup.GET("/exchange-rates", controllers.ListExchangeRates)
+		adminGroup.PUT("/exchange-rates", controllers.UpdateExchangeRates)
+
+		// 分类的属性定义，migrate 按定义规范化分类下已有的属性
+		adminGroup.POST("/categories/:id/attributes", controllers.CreateAttributeDefinition)
+		adminGroup.POST("/categories/:id/attributes/migrate", controllers.MigrateCategoryAttributes)
+		adminGroup.PUT("/categories/:id/attributes/:defId", controllers.UpdateAttributeDefinition)
+		adminGroup.DELETE("/categories/:id/attributes/:defId", controllers.DeleteAttributeDefinition)
+
+		// 促销规则
+		adminGroup.GET("/promotions", controllers.ListPromotions)
+		adminGroup.POST("/promotions", controllers.CreatePromotion)
+		adminGroup.GET("/promotions/:id", controllers.GetPromotion)
+		adminGroup.PUT("/promotions/:id", controllers.UpdatePromotion)
+		adminGroup.DELETE("/promotions/:id", controllers.DeletePromotion)
 	}
 
 	// 启动服务器
@@ -0,0 +1,142 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"product-service/database"
+	"product-service/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader       = "X-Request-ID"
+	apiKeyHeader          = "X-API-Key"
+	serviceIdentityHeader = "X-Service-Name"
+
+	auditEntityTypeKey = "auditEntityType"
+	auditEntityIDKey   = "auditEntityID"
+)
+
+// SetAuditEntity 由处理函数指定审计记录的目标实体（如创建操作生成的新ID）
+func SetAuditEntity(c *gin.Context, entityType string, entityID interface{}) {
+	c.Set(auditEntityTypeKey, entityType)
+	c.Set(auditEntityIDKey, fmt.Sprint(entityID))
+}
+
+// AuditMiddleware 记录所有写操作的审计日志
+// 需放在 AuthMiddleware 之前，以便认证失败的写请求同样被记录
+func AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Set("requestID", requestID)
+		c.Header(requestIDHeader, requestID)
+
+		if !isWriteMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		entry := models.AuditLog{
+			UserID: c.GetInt("userID"),
+			// 以下两项只是客户端的声明，本服务不校验 API 密钥和服务名
+			ClaimedAPIKeyFingerprint: fingerprintAPIKey(c.GetHeader(apiKeyHeader)),
+			ClaimedServiceIdentity:   c.GetHeader(serviceIdentityHeader),
+			ClientIP:                 c.ClientIP(),
+			RequestID:                requestID,
+			Method:                   c.Request.Method,
+			Route:                    c.FullPath(),
+			Path:                     c.Request.URL.Path,
+			EntityType:               auditEntityType(c),
+			EntityID:                 auditEntityID(c),
+			StatusCode:               c.Writer.Status(),
+			LatencyMs:                time.Since(start).Milliseconds(),
+		}
+		entry.Outcome = models.AuditOutcomeSuccess
+		if entry.StatusCode >= http.StatusBadRequest {
+			entry.Outcome = models.AuditOutcomeFailure
+		}
+
+		_, err := database.DB.Exec(`
+			INSERT INTO audit_logs
+			(user_id, api_key_fingerprint, service_identity, client_ip, request_id, method,
+			 route, path, entity_type, entity_id, status_code, outcome, latency_ms)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			entry.UserID, entry.ClaimedAPIKeyFingerprint, entry.ClaimedServiceIdentity, entry.ClientIP,
+			entry.RequestID, entry.Method, entry.Route, entry.Path, entry.EntityType,
+			entry.EntityID, entry.StatusCode, entry.Outcome, entry.LatencyMs,
+		)
+		if err != nil {
+			log.Printf("Failed to write audit log for %s %s: %v", entry.Method, entry.Path, err)
+		}
+	}
+}
+
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	_, _ = rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// fingerprintAPIKey 只保存API密钥的摘要，避免明文落库
+func fingerprintAPIKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// auditEntityType 优先使用处理函数指定的实体类型，否则根据路由推断，如 /api/products/:id -> product
+func auditEntityType(c *gin.Context) string {
+	if entityType := c.GetString(auditEntityTypeKey); entityType != "" {
+		return entityType
+	}
+
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	for _, segment := range strings.Split(strings.Trim(route, "/"), "/") {
+		if segment == "" || segment == "api" || segment == "admin" || strings.HasPrefix(segment, ":") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(segment, "ies"):
+			return strings.TrimSuffix(segment, "ies") + "y"
+		case strings.HasSuffix(segment, "s"):
+			return strings.TrimSuffix(segment, "s")
+		default:
+			return segment
+		}
+	}
+	return ""
+}
+
+func auditEntityID(c *gin.Context) string {
+	if entityID := c.GetString(auditEntityIDKey); entityID != "" {
+		return entityID
+	}
+	return c.Param("id")
+}
@@ -31,28 +31,60 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 提取令牌
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		if !authenticate(c, authHeader) {
+			return
+		}
+		c.Next()
+	}
+}
 
-		// 加载配置获取JWT密钥
-		cfg := config.LoadConfig()
-		jwtSecret := cfg.JWTSecret
+// OptionalAuthMiddleware 携带令牌时验证并设置用户信息，未携带时按匿名访问处理
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
+			return
+		}
 
-		// 验证令牌
-		userID, err := utils.ValidateToken(tokenString, jwtSecret)
-		if err != nil {
+		if !strings.HasPrefix(authHeader, "Bearer ") {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid token: " + err.Error(),
+				"error": "Invalid authorization format. Expected 'Bearer <token>'",
 			})
 			return
 		}
 
-		// 设置用户ID到上下文
-		c.Set("userID", userID)
+		if !authenticate(c, authHeader) {
+			return
+		}
 		c.Next()
 	}
 }
 
+// authenticate 验证 Bearer 令牌并将用户ID和客户分组写入上下文，失败时中止请求
+func authenticate(c *gin.Context, authHeader string) bool {
+	// 提取令牌
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+	// 加载配置获取JWT密钥
+	cfg := config.LoadConfig()
+	jwtSecret := cfg.JWTSecret
+
+	// 验证令牌
+	claims, err := utils.ValidateTokenClaims(tokenString, jwtSecret)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid token: " + err.Error(),
+		})
+		return false
+	}
+
+	// 设置用户ID和客户分组到上下文
+	c.Set("userID", claims.UserID)
+	c.Set("customerGroup", claims.CustomerGroup)
+	return true
+}
+
 // AdminMiddleware 验证管理员权限的中间件
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -65,11 +97,7 @@ func AdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		userID := userIDValue.(int)
-
-		// 在实际应用中，这里应该查询数据库验证用户角色
-		// 为简化演示，我们假设用户ID为1的是管理员
-		if userID != 1 {
+		if !isAdminUser(userIDValue.(int)) {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 				"error": "Admin privileges required",
 			})
@@ -80,12 +108,23 @@ func AdminMiddleware() gin.HandlerFunc {
 	}
 }
 
+// isAdminUser 在实际应用中，这里应该查询数据库验证用户角色
+// 为简化演示，我们假设用户ID为1的是管理员
+func isAdminUser(userID int) bool {
+	return userID == 1
+}
+
+// IsAdmin 当前请求的用户是否为管理员
+func IsAdmin(c *gin.Context) bool {
+	return isAdminUser(c.GetInt("userID"))
+}
+
 // CORSMiddleware 处理跨域请求的中间件
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Idempotency-Key, X-Request-ID, X-API-Key, X-Service-Name, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,265 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"product-service/config"
+	"product-service/database"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	idempotencyHeader       = "Idempotency-Key"
+	idempotencyReplayHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyWriter 在写出响应的同时缓存响应体，便于之后重放
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// storedResponse 已记录的幂等请求
+type storedResponse struct {
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	Active      bool
+}
+
+// IdempotencyMiddleware 基于 Idempotency-Key 请求头的幂等处理中间件
+// 需放在 AuthMiddleware 之后，键按用户隔离并存储在MySQL中，多副本共享
+func IdempotencyMiddleware() gin.HandlerFunc {
+	cfg := config.LoadConfig()
+	ttl, lease := cfg.IdempotencyTTL, cfg.IdempotencyLease
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key must not exceed 255 characters",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetInt("userID")
+		requestHash := hashIdempotentRequest(c.Request.Method, c.FullPath(), body)
+
+		// 每次占用使用新的令牌，占用过期被其他请求接管后，本请求不会再改动对方的记录
+		token := newRequestID()
+		reserved, err := reserveIdempotencyKey(userID, key, requestHash, token, lease)
+		if err != nil {
+			log.Printf("Failed to reserve idempotency key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		if !reserved {
+			stored, err := loadIdempotencyKey(userID, key)
+			if err != nil {
+				log.Printf("Failed to load idempotency key: %v", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			}
+
+			if stored != nil && stored.Active {
+				replayIdempotentResponse(c, stored, requestHash)
+				return
+			}
+
+			// 记录已被清理时重新插入，已过期时原子地接管，同时到达的重试只有一个能成功
+			if stored == nil {
+				reserved, err = reserveIdempotencyKey(userID, key, requestHash, token, lease)
+			} else {
+				reserved, err = takeOverIdempotencyKey(userID, key, requestHash, token, lease)
+			}
+			if err != nil {
+				log.Printf("Failed to reserve idempotency key: %v", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			}
+			if !reserved {
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+					"error": "A request with this Idempotency-Key is already in progress",
+				})
+				return
+			}
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// 处理过程中 panic 时释放占用，允许客户端使用同一个键重试
+		defer func() {
+			if r := recover(); r != nil {
+				releaseIdempotencyKey(userID, key, token)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		// 服务端错误不记录，允许客户端使用同一个键重试
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			releaseIdempotencyKey(userID, key, token)
+			return
+		}
+
+		result, err := database.DB.Exec(`
+			UPDATE idempotency_keys
+			SET status_code = ?, content_type = ?, response_body = ?,
+				expires_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+			WHERE user_id = ? AND idempotency_key = ? AND token = ?
+		`, status, writer.Header().Get("Content-Type"), writer.body.Bytes(), int(ttl.Seconds()), userID, key, token)
+		if err != nil {
+			log.Printf("Failed to record idempotent response: %v", err)
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			log.Printf("Idempotency key %q was taken over after its lease expired, response not recorded", key)
+		}
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, stored *storedResponse, requestHash string) {
+	if stored.RequestHash != requestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Idempotency-Key has already been used with a different request",
+		})
+		return
+	}
+
+	if stored.StatusCode == 0 {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "A request with this Idempotency-Key is already in progress",
+		})
+		return
+	}
+
+	c.Header(idempotencyReplayHeader, "true")
+	contentType := stored.ContentType
+	if contentType == "" {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Data(stored.StatusCode, contentType, stored.Body)
+	c.Abort()
+}
+
+func hashIdempotentRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// reserveIdempotencyKey 占用幂等键，键已存在时返回false
+// 占用只在 lease 内有效，进程在处理期间退出时键会在 lease 到期后自动释放
+func reserveIdempotencyKey(userID int, key, requestHash, token string, lease time.Duration) (bool, error) {
+	_, err := database.DB.Exec(`
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, token, expires_at)
+		VALUES (?, ?, ?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND))
+	`, userID, key, requestHash, token, int(lease.Seconds()))
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// takeOverIdempotencyKey 接管已过期的幂等键，键仍有效或已被其他请求接管时返回false
+func takeOverIdempotencyKey(userID int, key, requestHash, token string, lease time.Duration) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE idempotency_keys
+		SET request_hash = ?, token = ?, status_code = 0, content_type = '', response_body = NULL,
+			created_at = NOW(), expires_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+		WHERE user_id = ? AND idempotency_key = ? AND expires_at <= NOW()
+	`, requestHash, token, int(lease.Seconds()), userID, key)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func loadIdempotencyKey(userID int, key string) (*storedResponse, error) {
+	var stored storedResponse
+	err := database.DB.QueryRow(`
+		SELECT request_hash, status_code, content_type, COALESCE(response_body, ''), expires_at > NOW()
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?
+	`, userID, key).Scan(
+		&stored.RequestHash, &stored.StatusCode, &stored.ContentType, &stored.Body, &stored.Active,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// releaseIdempotencyKey 释放本请求的占用，键已被其他请求接管时不做任何操作
+func releaseIdempotencyKey(userID int, key, token string) {
+	_, err := database.DB.Exec(
+		"DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND token = ?",
+		userID, key, token,
+	)
+	if err != nil {
+		log.Printf("Failed to delete idempotency key: %v", err)
+	}
+}
+
+// StartIdempotencyCleanup 定期清理过期的幂等键
+func StartIdempotencyCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := database.DB.Exec("DELETE FROM idempotency_keys WHERE expires_at < NOW() LIMIT 1000"); err != nil {
+			log.Printf("Failed to clean up idempotency keys: %v", err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 属性值类型
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeEnum    = "enum"
+	// AttributeTypeUnit 带单位的数值，如 "15.6 in"，保存时换算为定义的单位
+	AttributeTypeUnit = "unit"
+)
+
+// AttributeDefinition 分类下的属性定义。商品属性名与 Name 或 Aliases 不区分大小写匹配时
+// 统一为 Name，并按 Type 校验和规范属性值
+type AttributeDefinition struct {
+	ID         int      `json:"id"`
+	CategoryID int      `json:"category_id"`
+	Name       string   `json:"name" binding:"required"`
+	Type       string   `json:"type" binding:"required,oneof=string number boolean enum unit"`
+	Aliases    []string `json:"aliases"`
+	// AllowedValues enum 类型的可选值
+	AllowedValues []string `json:"allowed_values"`
+	// Unit unit 类型保存时使用的单位，须为支持的重量或长度单位
+	Unit string `json:"unit"`
+	// Required 发布商品时必须设置该属性
+	Required  bool      `json:"required"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Matches 属性名是否与定义的名称或别名相同（不区分大小写）
+func (d *AttributeDefinition) Matches(name string) bool {
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, d.Name) {
+		return true
+	}
+	for _, alias := range d.Aliases {
+		if strings.EqualFold(name, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeValue 按定义的类型校验属性值并返回规范形式
+func (d *AttributeDefinition) NormalizeValue(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("attribute %s must not be empty", d.Name)
+	}
+
+	switch d.Type {
+	case AttributeTypeNumber:
+		number, ok := parseAttributeNumber(value)
+		if !ok {
+			return "", fmt.Errorf("attribute %s must be a number", d.Name)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case AttributeTypeBoolean:
+		switch strings.ToLower(value) {
+		case "true", "yes", "1":
+			return "true", nil
+		case "false", "no", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("attribute %s must be true or false", d.Name)
+	case AttributeTypeEnum:
+		for _, allowed := range d.AllowedValues {
+			if strings.EqualFold(value, allowed) {
+				return allowed, nil
+			}
+		}
+		return "", fmt.Errorf("attribute %s must be one of %s", d.Name, strings.Join(d.AllowedValues, ", "))
+	case AttributeTypeUnit:
+		// 未带单位时视为定义的单位
+		fields := strings.Fields(value)
+		if len(fields) > 2 {
+			return "", fmt.Errorf("attribute %s must be a number with an optional unit, e.g. \"15.6 %s\"", d.Name, d.Unit)
+		}
+		number, ok := parseAttributeNumber(fields[0])
+		if !ok {
+			return "", fmt.Errorf("attribute %s must be a number with an optional unit, e.g. \"15.6 %s\"", d.Name, d.Unit)
+		}
+		if len(fields) == 2 {
+			converted, ok := ConvertUnit(number, strings.ToLower(fields[1]), d.Unit)
+			if !ok {
+				return "", fmt.Errorf("attribute %s cannot be converted from %s to %s", d.Name, fields[1], d.Unit)
+			}
+			number = converted
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64) + " " + d.Unit, nil
+	}
+	return value, nil
+}
+
+// parseAttributeNumber 解析有限的十进制数
+func parseAttributeNumber(s string) (float64, bool) {
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+// AttributeNumber 属性值为数值或“数值 单位”时返回其中的数值，用于数值范围过滤
+func AttributeNumber(value string) (float64, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, false
+	}
+	return parseAttributeNumber(fields[0])
+}
+
+// Normalize 校验定义本身：去掉重复的别名和可选值，检查类型所需的字段
+func (d *AttributeDefinition) Normalize() error {
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" {
+		return errors.New("name is required")
+	}
+
+	seen := map[string]bool{strings.ToLower(d.Name): true}
+	var aliases []string
+	for _, alias := range d.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" || seen[strings.ToLower(alias)] {
+			continue
+		}
+		seen[strings.ToLower(alias)] = true
+		aliases = append(aliases, alias)
+	}
+	d.Aliases = aliases
+
+	var allowed []string
+	seen = map[string]bool{}
+	for _, value := range d.AllowedValues {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		allowed = append(allowed, value)
+	}
+	d.AllowedValues = allowed
+
+	switch d.Type {
+	case AttributeTypeEnum:
+		if len(d.AllowedValues) == 0 {
+			return errors.New("allowed_values is required for enum attributes")
+		}
+	case AttributeTypeUnit:
+		d.Unit = strings.ToLower(strings.TrimSpace(d.Unit))
+		if !IsMeasureUnit(d.Unit) {
+			return fmt.Errorf("unit must be a weight or length unit, got %q", d.Unit)
+		}
+	}
+	if d.Type != AttributeTypeEnum {
+		d.AllowedValues = nil
+	}
+	if d.Type != AttributeTypeUnit {
+		d.Unit = ""
+	}
+	return nil
+}
+
+// AttributeMigrationIssue 迁移时无法按定义规范化的属性值，或与同一商品的其他属性对应同一定义的属性
+type AttributeMigrationIssue struct {
+	ProductID   int    `json:"product_id"`
+	AttributeID int    `json:"attribute_id"`
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Error       string `json:"error"`
+}
+
+// AttributeMigrationResult 按属性定义规范化分类下已有的自由属性的结果
+type AttributeMigrationResult struct {
+	DryRun bool `json:"dry_run"`
+	// Renamed、Normalized 属性名或属性值被修改的数量，Products 受影响的商品数量
+	Renamed    int                       `json:"renamed"`
+	Normalized int                       `json:"normalized"`
+	Products   int                       `json:"products"`
+	Invalid    []AttributeMigrationIssue `json:"invalid"`
+}
+
+// AttributeRange 数值属性的范围过滤，Min、Max 为空表示不限
+type AttributeRange struct {
+	Min *float64
+	Max *float64
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// 审计结果
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditLog 写操作审计记录，只追加不修改。UserID 来自验证过的令牌；
+// ClaimedAPIKeyFingerprint、ClaimedServiceIdentity 取自客户端请求头，未经验证，不能作为身份依据
+type AuditLog struct {
+	ID                       int64     `json:"id"`
+	OccurredAt               time.Time `json:"occurred_at"`
+	UserID                   int       `json:"user_id"`
+	ClaimedAPIKeyFingerprint string    `json:"claimed_api_key_fingerprint,omitempty"`
+	ClaimedServiceIdentity   string    `json:"claimed_service_identity,omitempty"`
+	ClientIP                 string    `json:"client_ip"`
+	RequestID                string    `json:"request_id"`
+	Method                   string    `json:"method"`
+	Route                    string    `json:"route"`
+	Path                     string    `json:"path"`
+	EntityType               string    `json:"entity_type"`
+	EntityID                 string    `json:"entity_id,omitempty"`
+	StatusCode               int       `json:"status_code"`
+	Outcome                  string    `json:"outcome"`
+	LatencyMs                int64     `json:"latency_ms"`
+}
+
+// AuditFilter 审计日志查询条件
+type AuditFilter struct {
+	UserID     int    `form:"user_id"`
+	EntityType string `form:"entity_type"`
+	EntityID   string `form:"entity_id"`
+	Route      string `form:"route"`
+	Method     string `form:"method"`
+	Outcome    string `form:"outcome" binding:"omitempty,oneof=success failure"`
+	RequestID  string `form:"request_id"`
+	From       string `form:"from"`
+	To         string `form:"to"`
+	Format     string `form:"format" binding:"omitempty,oneof=json csv"`
+}
+
+type AuditLogResponse struct {
+	Logs      []AuditLog `json:"logs"`
+	Total     int        `json:"total"`
+	Page      int        `json:"page"`
+	PageSize  int        `json:"page_size"`
+	TotalPage int        `json:"total_page"`
+}
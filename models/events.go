@@ -7,12 +7,34 @@ import (
 
 // 事件类型常量
 const (
-	EventProductCreated  = "product_created"
-	EventProductUpdated  = "product_updated"
-	EventProductDeleted  = "product_deleted"
-	EventCategoryCreated = "category_created"
-	EventImageAdded      = "image_added"
-	EventAttributeAdded  = "attribute_added"
+	EventProductCreated   = "product_created"
+	EventProductUpdated   = "product_updated"
+	EventProductDeleted   = "product_deleted"
+	EventProductRestored  = "product_restored"
+	EventProductPurged    = "product_purged"
+	EventProductsImported = "products_imported"
+	EventCategoryCreated  = "category_created"
+	EventImageAdded       = "image_added"
+	EventImageUpdated     = "image_updated"
+	EventImageRemoved     = "image_removed"
+	EventAttributeAdded   = "attribute_added"
+	EventAttributeUpdated = "attribute_updated"
+	EventAttributeRemoved = "attribute_removed"
+	EventProductPublished = "product_published"
+	EventProductArchived  = "product_archived"
+	// 搜索同义词或停用词变更，各副本收到后重新加载
+	EventSearchDictionaryUpdated = "search_dictionary_updated"
+	// 外部服务推送的汇率更新，由消费商品队列的副本写入数据库
+	EventRatesUpdated = "rates_updated"
+	// 汇率已写入数据库，各副本收到后重新加载
+	EventExchangeRatesChanged = "exchange_rates_changed"
+	// 价格计划生效或结束导致的价格变化
+	EventPriceChanged = "price_changed"
+	// 促销进入或离开生效期
+	EventPromotionStarted = "promotion_started"
+	EventPromotionEnded   = "promotion_ended"
+	// 促销规则变更，各副本收到后重新加载
+	EventPromotionsUpdated = "promotions_updated"
 )
 
 // ProductEvent 商品事件结构
@@ -25,6 +47,14 @@ type ProductEvent struct {
 	CategoryID  int              `json:"category_id,omitempty"`
 	ImageData   ProductImage     `json:"image_data,omitempty"`
 	Attribute   ProductAttribute `json:"attribute_data,omitempty"`
+	ProductIDs  []int            `json:"product_ids,omitempty"`
+	// RatesBase、Rates 为 rates_updated 消息携带的汇率
+	RatesBase string         `json:"base,omitempty"`
+	Rates     []ExchangeRate `json:"rates,omitempty"`
+	// PriceChange 为 price_changed 事件的价格变化
+	PriceChange *PriceChange `json:"price_change,omitempty"`
+	// Promotion 为 promotion_started/promotion_ended 事件的促销
+	Promotion *Promotion `json:"promotion_data,omitempty"`
 }
 
 // ToJSON 将事件转换为JSON
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 汇率换算结果的舍入方式
+const (
+	RoundingHalfUp = "half_up"
+	RoundingDown   = "down"
+	RoundingUp     = "up"
+)
+
+// ExchangeRate 1 单位基础币种可兑换的 Currency 数量，Rate 为十进制数字或字符串
+type ExchangeRate struct {
+	Currency  string      `json:"currency" binding:"required"`
+	Rate      json.Number `json:"rate" binding:"required"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// ExchangeRatesUpdate 批量更新汇率。Base 为空时表示默认币种；
+// 不是默认币种时 Rates 中必须包含默认币种，用于换算为以默认币种为基准的汇率
+type ExchangeRatesUpdate struct {
+	Base  string         `json:"base"`
+	Rates []ExchangeRate `json:"rates" binding:"required,min=1,dive"`
+}
+
+// AppliedExchangeRate 展示价格使用的汇率，UpdatedAt 为参与换算的汇率中较早的更新时间
+type AppliedExchangeRate struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GTINLength 规范化后的 GTIN 长度，GTIN-8/12/13 左侧补零为 GTIN-14
+const GTINLength = 14
+
+// NormalizeGTIN 校验 GTIN-8、UPC-A（GTIN-12）、EAN-13（GTIN-13）或 GTIN-14 的校验位，
+// 去掉空格和连字符后左侧补零为 GTIN-14，不同设备扫描同一条码得到相同的值
+func NormalizeGTIN(code string) (string, error) {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	switch len(digits) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("gtin %q must have 8, 12, 13 or 14 digits", code)
+	}
+	if !isDigits(digits) {
+		return "", fmt.Errorf("gtin %q must contain only digits", code)
+	}
+	digits = strings.Repeat("0", GTINLength-len(digits)) + digits
+
+	if check := gtinCheckDigit(digits[:GTINLength-1]); digits[GTINLength-1] != check {
+		return "", fmt.Errorf("gtin %q has an invalid check digit", code)
+	}
+	return digits, nil
+}
+
+// gtinCheckDigit GS1 校验位：从右往左奇数位乘 3、偶数位乘 1，补足到 10 的倍数
+func gtinCheckDigit(body string) byte {
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		d := int(body[i] - '0')
+		if (len(body)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,49 @@
+package models
+
+import "testing"
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{"GTIN-8", "96385074", "00000096385074", false},
+		{"UPC-A", "036000291452", "00036000291452", false},
+		{"EAN-13", "4006381333931", "04006381333931", false},
+		{"EAN-13 another", "5901234123457", "05901234123457", false},
+		{"GTIN-14", "10012345678902", "10012345678902", false},
+		{"already normalized", "04006381333931", "04006381333931", false},
+		{"check digit zero", "00000000", "00000000000000", false},
+		{"spaces and hyphens", " 400-6381 333931 ", "04006381333931", false},
+		{"UPC-A and EAN-13 of the same item match", "0036000291452", "00036000291452", false},
+
+		{"wrong check digit", "4006381333932", "", true},
+		{"wrong GTIN-8 check digit", "96385075", "", true},
+		{"swapped digits", "4006381339331", "", true},
+		{"unsupported length", "123456789", "", true},
+		{"too long", "123456789012345", "", true},
+		{"empty", "", "", true},
+		{"letters", "40063813339A1", "", true},
+		{"other separators", "4006381.333931", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeGTIN(tt.code)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NormalizeGTIN(%q) = %q, want error", tt.code, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeGTIN(%q) returned error: %v", tt.code, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeGTIN(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
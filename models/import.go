@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// 导入任务状态
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// 导入文件格式
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// 导入事件模式：逐个商品发送事件，或整批发送一个事件
+const (
+	ImportEventsPerProduct = "per_product"
+	ImportEventsBatch      = "batch"
+)
+
+// ImportJob 商品批量导入任务
+type ImportJob struct {
+	ID            int        `json:"id"`
+	Status        string     `json:"status"`
+	Format        string     `json:"format"`
+	EventMode     string     `json:"event_mode"`
+	TotalRows     int        `json:"total_rows"`
+	ProcessedRows int        `json:"processed_rows"`
+	CreatedCount  int        `json:"created_count"`
+	UpdatedCount  int        `json:"updated_count"`
+	FailedCount   int        `json:"failed_count"`
+	CreatedBy     int        `json:"created_by"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
+
+// ImportRowError 导入任务中单行的错误
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
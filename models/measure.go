@@ -0,0 +1,295 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 重量单位
+const (
+	WeightUnitGram     = "g"
+	WeightUnitKilogram = "kg"
+	WeightUnitOunce    = "oz"
+	WeightUnitPound    = "lb"
+)
+
+// 长度单位
+const (
+	LengthUnitMillimetre = "mm"
+	LengthUnitCentimetre = "cm"
+	LengthUnitMetre      = "m"
+	LengthUnitInch       = "in"
+	LengthUnitFoot       = "ft"
+)
+
+// WeightUnitGrams 每个重量单位对应的克数
+var WeightUnitGrams = map[string]float64{
+	WeightUnitGram:     1,
+	WeightUnitKilogram: 1000,
+	WeightUnitOunce:    28.349523125,
+	WeightUnitPound:    453.59237,
+}
+
+// LengthUnitMillimetres 每个长度单位对应的毫米数
+var LengthUnitMillimetres = map[string]float64{
+	LengthUnitMillimetre: 1,
+	LengthUnitCentimetre: 10,
+	LengthUnitMetre:      1000,
+	LengthUnitInch:       25.4,
+	LengthUnitFoot:       304.8,
+}
+
+// 包装重量上限 1 吨，单边尺寸上限 100 米
+const (
+	maxWeightGrams       = 1e6
+	maxLengthMillimetres = 1e5
+)
+
+// 单位换算后保留的小数位数
+const measureDecimals = 3
+
+// NormalizeWeightUnit 转为小写并校验是否为支持的重量单位
+func NormalizeWeightUnit(unit string) (string, error) {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	if _, ok := WeightUnitGrams[u]; !ok {
+		return "", fmt.Errorf("unsupported weight unit %q", unit)
+	}
+	return u, nil
+}
+
+// NormalizeLengthUnit 转为小写并校验是否为支持的长度单位
+func NormalizeLengthUnit(unit string) (string, error) {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	if _, ok := LengthUnitMillimetres[u]; !ok {
+		return "", fmt.Errorf("unsupported length unit %q", unit)
+	}
+	return u, nil
+}
+
+// roundMeasure 换算结果保留 measureDecimals 位小数
+func roundMeasure(value float64) float64 {
+	scale := math.Pow10(measureDecimals)
+	return math.Round(value*scale) / scale
+}
+
+// validMeasure 数值须为有限的正数且不超过上限（以基准单位计）
+func validMeasure(value, factor, max float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0) && value > 0 && value*factor <= max
+}
+
+// Weight 包装重量，Value 为空表示未设置
+type Weight struct {
+	Value *float64 `json:"value"`
+	Unit  string   `json:"unit"`
+}
+
+// IsSet 是否设置了重量
+func (w Weight) IsSet() bool {
+	return w.Value != nil
+}
+
+// Normalize 校验重量并规范单位，数值和单位须同时提供
+func (w *Weight) Normalize() error {
+	if w.Value == nil {
+		if strings.TrimSpace(w.Unit) != "" {
+			return errors.New("weight value is required when unit is set")
+		}
+		w.Unit = ""
+		return nil
+	}
+	if strings.TrimSpace(w.Unit) == "" {
+		return errors.New("weight unit is required")
+	}
+	unit, err := NormalizeWeightUnit(w.Unit)
+	if err != nil {
+		return err
+	}
+	if !validMeasure(*w.Value, WeightUnitGrams[unit], maxWeightGrams) {
+		return fmt.Errorf("weight must be positive and at most %g kg", maxWeightGrams/1000)
+	}
+	w.Unit = unit
+	return nil
+}
+
+// Grams 以克表示的重量，未设置时返回 false
+func (w Weight) Grams() (float64, bool) {
+	if w.Value == nil {
+		return 0, false
+	}
+	return *w.Value * WeightUnitGrams[w.Unit], true
+}
+
+// In 换算为指定单位，未设置或单位相同时原样返回
+func (w Weight) In(unit string) Weight {
+	grams, ok := w.Grams()
+	if !ok || unit == w.Unit {
+		return w
+	}
+	value := roundMeasure(grams / WeightUnitGrams[unit])
+	return Weight{Value: &value, Unit: unit}
+}
+
+// String 格式化为 "1.5 kg"，未设置时为空
+func (w Weight) String() string {
+	if w.Value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*w.Value, 'f', -1, 64) + " " + w.Unit
+}
+
+// MarshalJSON 未设置时输出 null
+func (w Weight) MarshalJSON() ([]byte, error) {
+	if w.Value == nil {
+		return []byte("null"), nil
+	}
+	type weight Weight
+	return json.Marshal(weight(w))
+}
+
+// ParseWeight 解析 "1.5 kg" 形式的重量，空字符串表示未设置
+func ParseWeight(s string) (Weight, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Weight{}, nil
+	}
+	if len(fields) != 2 {
+		return Weight{}, fmt.Errorf("invalid weight %q, expected e.g. \"1.5 kg\"", s)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return Weight{}, fmt.Errorf("invalid weight %q", s)
+	}
+	w := Weight{Value: &value, Unit: fields[1]}
+	if err := w.Normalize(); err != nil {
+		return Weight{}, err
+	}
+	return w, nil
+}
+
+// Dimensions 包装尺寸，长宽高使用同一单位，全部为空表示未设置
+type Dimensions struct {
+	Length *float64 `json:"length"`
+	Width  *float64 `json:"width"`
+	Height *float64 `json:"height"`
+	Unit   string   `json:"unit"`
+}
+
+// IsSet 是否设置了尺寸
+func (d Dimensions) IsSet() bool {
+	return d.Length != nil
+}
+
+// Normalize 校验尺寸并规范单位，长宽高和单位须同时提供
+func (d *Dimensions) Normalize() error {
+	if d.Length == nil && d.Width == nil && d.Height == nil {
+		if strings.TrimSpace(d.Unit) != "" {
+			return errors.New("dimensions length, width and height are required when unit is set")
+		}
+		d.Unit = ""
+		return nil
+	}
+	if d.Length == nil || d.Width == nil || d.Height == nil {
+		return errors.New("dimensions require length, width and height")
+	}
+	if strings.TrimSpace(d.Unit) == "" {
+		return errors.New("dimensions unit is required")
+	}
+	unit, err := NormalizeLengthUnit(d.Unit)
+	if err != nil {
+		return err
+	}
+	factor := LengthUnitMillimetres[unit]
+	for _, value := range []float64{*d.Length, *d.Width, *d.Height} {
+		if !validMeasure(value, factor, maxLengthMillimetres) {
+			return fmt.Errorf("dimensions must be positive and at most %g m", maxLengthMillimetres/1000)
+		}
+	}
+	d.Unit = unit
+	return nil
+}
+
+// Millimetres 以毫米表示的长、宽、高，未设置时返回 false
+func (d Dimensions) Millimetres() (length, width, height float64, ok bool) {
+	if !d.IsSet() {
+		return 0, 0, 0, false
+	}
+	factor := LengthUnitMillimetres[d.Unit]
+	return *d.Length * factor, *d.Width * factor, *d.Height * factor, true
+}
+
+// In 换算为指定单位，未设置或单位相同时原样返回
+func (d Dimensions) In(unit string) Dimensions {
+	length, width, height, ok := d.Millimetres()
+	if !ok || unit == d.Unit {
+		return d
+	}
+	factor := LengthUnitMillimetres[unit]
+	length, width, height = roundMeasure(length/factor), roundMeasure(width/factor), roundMeasure(height/factor)
+	return Dimensions{Length: &length, Width: &width, Height: &height, Unit: unit}
+}
+
+// String 格式化为 "30x20x10 cm"，未设置时为空
+func (d Dimensions) String() string {
+	if !d.IsSet() {
+		return ""
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return format(*d.Length) + "x" + format(*d.Width) + "x" + format(*d.Height) + " " + d.Unit
+}
+
+// MarshalJSON 未设置时输出 null
+func (d Dimensions) MarshalJSON() ([]byte, error) {
+	if !d.IsSet() {
+		return []byte("null"), nil
+	}
+	type dimensions Dimensions
+	return json.Marshal(dimensions(d))
+}
+
+// ParseDimensions 解析 "30x20x10 cm" 形式的尺寸（长x宽x高），空字符串表示未设置
+func ParseDimensions(s string) (Dimensions, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Dimensions{}, nil
+	}
+	parts := strings.Split(strings.ToLower(fields[0]), "x")
+	if len(fields) != 2 || len(parts) != 3 {
+		return Dimensions{}, fmt.Errorf("invalid dimensions %q, expected e.g. \"30x20x10 cm\"", s)
+	}
+	values := make([]float64, 3)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return Dimensions{}, fmt.Errorf("invalid dimensions %q", s)
+		}
+		values[i] = value
+	}
+	d := Dimensions{Length: &values[0], Width: &values[1], Height: &values[2], Unit: fields[1]}
+	if err := d.Normalize(); err != nil {
+		return Dimensions{}, err
+	}
+	return d, nil
+}
+
+// ConvertUnit 在同一类单位（重量或长度）之间换算，单位不属于同一类时返回 false
+func ConvertUnit(value float64, from, to string) (float64, bool) {
+	for _, factors := range []map[string]float64{WeightUnitGrams, LengthUnitMillimetres} {
+		fromFactor, okFrom := factors[from]
+		toFactor, okTo := factors[to]
+		if okFrom && okTo {
+			return roundMeasure(value * fromFactor / toFactor), true
+		}
+	}
+	return 0, false
+}
+
+// IsMeasureUnit 是否为支持的重量或长度单位
+func IsMeasureUnit(unit string) bool {
+	_, weight := WeightUnitGrams[unit]
+	_, length := LengthUnitMillimetres[unit]
+	return weight || length
+}
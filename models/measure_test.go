@@ -0,0 +1,80 @@
+package models
+
+import "testing"
+
+func TestConvertUnit(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  float64
+		from   string
+		to     string
+		want   float64
+		wantOK bool
+	}{
+		{"kilograms to grams", 1.5, WeightUnitKilogram, WeightUnitGram, 1500, true},
+		{"grams to kilograms", 250, WeightUnitGram, WeightUnitKilogram, 0.25, true},
+		{"pounds to kilograms rounds to 3 decimals", 1, WeightUnitPound, WeightUnitKilogram, 0.454, true},
+		{"ounces to grams", 10, WeightUnitOunce, WeightUnitGram, 283.495, true},
+		{"pounds to ounces", 1, WeightUnitPound, WeightUnitOunce, 16, true},
+		{"inches to centimetres", 1, LengthUnitInch, LengthUnitCentimetre, 2.54, true},
+		{"feet to inches", 1, LengthUnitFoot, LengthUnitInch, 12, true},
+		{"metres to millimetres", 0.75, LengthUnitMetre, LengthUnitMillimetre, 750, true},
+		{"millimetres to inches", 1, LengthUnitMillimetre, LengthUnitInch, 0.039, true},
+		{"same unit still rounded", 1.23456, WeightUnitGram, WeightUnitGram, 1.235, true},
+		{"tiny value rounds to zero", 0.0004, WeightUnitGram, WeightUnitGram, 0, true},
+		{"zero", 0, LengthUnitMetre, LengthUnitCentimetre, 0, true},
+
+		{"weight to length", 1, WeightUnitKilogram, LengthUnitCentimetre, 0, false},
+		{"length to weight", 1, LengthUnitInch, WeightUnitOunce, 0, false},
+		{"unknown source unit", 1, "st", WeightUnitGram, 0, false},
+		{"unknown target unit", 1, WeightUnitGram, "yd", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ConvertUnit(tt.value, tt.from, tt.to)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ConvertUnit(%g, %q, %q) = %g, %v, want %g, %v", tt.value, tt.from, tt.to, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWeightIn(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		weight Weight
+		unit   string
+		want   Weight
+	}{
+		{"grams to kilograms", Weight{Value: value(500), Unit: WeightUnitGram}, WeightUnitKilogram, Weight{Value: value(0.5), Unit: WeightUnitKilogram}},
+		{"kilograms to pounds", Weight{Value: value(1.5), Unit: WeightUnitKilogram}, WeightUnitPound, Weight{Value: value(3.307), Unit: WeightUnitPound}},
+		{"ounces to grams", Weight{Value: value(10), Unit: WeightUnitOunce}, WeightUnitGram, Weight{Value: value(283.495), Unit: WeightUnitGram}},
+		{"pounds to ounces", Weight{Value: value(2), Unit: WeightUnitPound}, WeightUnitOunce, Weight{Value: value(32), Unit: WeightUnitOunce}},
+		{"same unit is not rounded", Weight{Value: value(1.23456), Unit: WeightUnitKilogram}, WeightUnitKilogram, Weight{Value: value(1.23456), Unit: WeightUnitKilogram}},
+		{"unset weight", Weight{}, WeightUnitKilogram, Weight{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.weight.In(tt.unit)
+			if got.String() != tt.want.String() || got.Unit != tt.want.Unit || got.IsSet() != tt.want.IsSet() {
+				t.Errorf("%v.In(%q) = %v, want %v", tt.weight, tt.unit, got, tt.want)
+			}
+			if got.IsSet() && *got.Value != *tt.want.Value {
+				t.Errorf("%v.In(%q) value = %g, want %g", tt.weight, tt.unit, *got.Value, *tt.want.Value)
+			}
+		})
+	}
+}
+
+func TestWeightInDoesNotModifyOriginal(t *testing.T) {
+	v := 1000.0
+	w := Weight{Value: &v, Unit: WeightUnitGram}
+	_ = w.In(WeightUnitKilogram)
+	if *w.Value != 1000 || w.Unit != WeightUnitGram {
+		t.Errorf("In modified the original weight: %v", w)
+	}
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency 未指定币种时使用的基础币种，启动时由配置设置
+var DefaultCurrency = "CNY"
+
+// currencyExponents ISO 4217 币种的小数位数
+var currencyExponents = map[string]int{
+	"AED": 2, "AUD": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "MOP": 2,
+	"MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2, "PHP": 2, "PLN": 2, "RUB": 2, "SAR": 2,
+	"SEK": 2, "SGD": 2, "THB": 2, "TRY": 2, "TWD": 2, "USD": 2, "ZAR": 2,
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "VND": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// 金额字符串最多的有效数字，保证不超出 int64
+const maxMoneyDigits = 18
+
+// CurrencyExponent 返回币种的小数位数
+func CurrencyExponent(currency string) (int, bool) {
+	exp, ok := currencyExponents[currency]
+	return exp, ok
+}
+
+// NormalizeCurrency 转为大写并校验是否为支持的币种
+func NormalizeCurrency(currency string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(currency))
+	if _, ok := currencyExponents[code]; !ok {
+		return "", fmt.Errorf("unsupported currency %q", currency)
+	}
+	return code, nil
+}
+
+// Money 以最小货币单位（如分）保存的金额
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// ParseMoney 将十进制金额字符串（主单位，如 "19.99"）精确转换为最小货币单位，小数位超出币种精度时报错
+func ParseMoney(amount, currency string) (Money, error) {
+	code, err := NormalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	exp := currencyExponents[code]
+
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > exp {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places for %s", amount, exp, code)
+	}
+	digits := strings.TrimLeft(intPart+fracPart+strings.Repeat("0", exp-len(fracPart)), "0")
+	if len(digits) > maxMoneyDigits {
+		return Money{}, fmt.Errorf("amount %q is too large", amount)
+	}
+
+	var minor int64
+	if digits != "" {
+		if minor, err = strconv.ParseInt(digits, 10, 64); err != nil {
+			return Money{}, fmt.Errorf("invalid amount %q", amount)
+		}
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: code}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal 以主单位表示的十进制字符串，如 1999 分为 "19.99"
+func (m Money) Decimal() string {
+	exp := currencyExponents[m.Currency]
+
+	negative := m.Amount < 0
+	abs := m.Amount
+	if negative {
+		abs = -abs
+	}
+	s := strconv.FormatInt(abs, 10)
+	if exp > 0 {
+		if len(s) <= exp {
+			s = strings.Repeat("0", exp-len(s)+1) + s
+		}
+		s = s[:len(s)-exp] + "." + s[len(s)-exp:]
+	}
+	if negative {
+		s = "-" + s
+	}
+	return s
+}
+
+// String 金额及币种，如 "19.99 CNY"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// IsPositive 金额是否大于零
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON 金额以十进制字符串输出，避免客户端按浮点数解析产生误差
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON 接受 {"amount": "19.99", "currency": "USD"}，amount 也可为数字；
+// 兼容旧格式的纯数字或字符串，此时使用默认币种
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+
+	var raw struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	amount, currency := data, DefaultCurrency
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		if len(raw.Amount) == 0 {
+			return errors.New("money amount is required")
+		}
+		amount = raw.Amount
+		if raw.Currency != "" {
+			currency = raw.Currency
+		}
+	}
+
+	var text string
+	if len(amount) > 0 && amount[0] == '"' {
+		if err := json.Unmarshal(amount, &text); err != nil {
+			return err
+		}
+	} else {
+		// 数字按原始文本解析，不经过 float64
+		var number json.Number
+		if err := json.Unmarshal(amount, &number); err != nil {
+			return fmt.Errorf("invalid money amount %s", amount)
+		}
+		text = number.String()
+	}
+
+	parsed, err := ParseMoney(text, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,85 @@
+package models
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{"two decimals", "19.99", "CNY", Money{1999, "CNY"}, false},
+		{"integer", "20", "USD", Money{2000, "USD"}, false},
+		{"one decimal padded", "0.5", "EUR", Money{50, "EUR"}, false},
+		{"trailing dot", "5.", "CNY", Money{500, "CNY"}, false},
+		{"trailing zeros beyond exponent", "1.2300", "USD", Money{123, "USD"}, false},
+		{"leading zeros", "007.10", "USD", Money{710, "USD"}, false},
+		{"surrounding whitespace", " 3.50 ", "CNY", Money{350, "CNY"}, false},
+		{"lower case currency", "1", "cny", Money{100, "CNY"}, false},
+		{"negative", "-2.25", "USD", Money{-225, "USD"}, false},
+		{"zero", "0", "USD", Money{0, "USD"}, false},
+		{"negative zero", "-0.00", "USD", Money{0, "USD"}, false},
+		{"zero exponent currency", "1500", "JPY", Money{1500, "JPY"}, false},
+		{"zero exponent with zero fraction", "1500.0", "JPY", Money{1500, "JPY"}, false},
+		{"three decimal currency", "1.234", "KWD", Money{1234, "KWD"}, false},
+		{"three decimal currency padded", "1.2", "KWD", Money{1200, "KWD"}, false},
+		{"maximum digits", "9999999999999999.99", "USD", Money{999999999999999999, "USD"}, false},
+
+		{"too many decimals", "19.999", "USD", Money{}, true},
+		{"fraction for zero exponent currency", "1500.5", "JPY", Money{}, true},
+		{"too many digits", "10000000000000000.00", "USD", Money{}, true},
+		{"empty", "", "USD", Money{}, true},
+		{"only sign", "-", "USD", Money{}, true},
+		{"missing integer part", ".5", "USD", Money{}, true},
+		{"plus sign", "+5", "USD", Money{}, true},
+		{"exponent notation", "1e3", "USD", Money{}, true},
+		{"thousands separator", "1,000", "USD", Money{}, true},
+		{"two dots", "1.2.3", "USD", Money{}, true},
+		{"unsupported currency", "1", "XXX", Money{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.amount, tt.currency)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q, %q) = %+v, want error", tt.amount, tt.currency, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q, %q) returned error: %v", tt.amount, tt.currency, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q, %q) = %+v, want %+v", tt.amount, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{1999, "CNY"}, "19.99"},
+		{Money{5, "USD"}, "0.05"},
+		{Money{0, "USD"}, "0.00"},
+		{Money{-225, "USD"}, "-2.25"},
+		{Money{1500, "JPY"}, "1500"},
+		{Money{1, "KWD"}, "0.001"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.Decimal(); got != tt.want {
+			t.Errorf("%+v.Decimal() = %q, want %q", tt.money, got, tt.want)
+		}
+		// Decimal 的结果应能原样解析回相同的金额
+		parsed, err := ParseMoney(tt.money.Decimal(), tt.money.Currency)
+		if err != nil || parsed != tt.money {
+			t.Errorf("ParseMoney(%q) = %+v, %v, want %+v", tt.money.Decimal(), parsed, err, tt.money)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// 价格计划状态
+const (
+	PriceSchedulePending   = "pending"
+	PriceScheduleActive    = "active"
+	PriceScheduleEnded     = "ended"
+	PriceScheduleCancelled = "cancelled"
+	// PriceScheduleSkipped 错过了生效时间或商品已删除，未生效
+	PriceScheduleSkipped = "skipped"
+)
+
+// PriceSchedule 商品在某个币种下的计划价格，在 [StartsAt, EndsAt) 期间生效，
+// 结束后恢复为生效前的价格。EndsAt 为空表示生效后不再自动恢复
+type PriceSchedule struct {
+	ID        int        `json:"id"`
+	ProductID int        `json:"product_id"`
+	Price     Money      `json:"price"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	Status    string     `json:"status"`
+	// PreviousPrice 生效前的价格，生效前或生效前没有该币种价格时为空
+	PreviousPrice *Money     `json:"previous_price,omitempty"`
+	CreatedBy     int        `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ActivatedAt   *time.Time `json:"activated_at,omitempty"`
+	EndedAt       *time.Time `json:"ended_at,omitempty"`
+}
+
+// PriceScheduleRequest 创建价格计划，Currency 为空时使用商品的基础币种
+type PriceScheduleRequest struct {
+	Amount   string     `json:"amount" binding:"required"`
+	Currency string     `json:"currency"`
+	StartsAt time.Time  `json:"starts_at" binding:"required"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+// PriceChange price_changed 事件携带的价格变化，价格为空表示该币种没有价格
+type PriceChange struct {
+	Currency   string `json:"currency"`
+	OldPrice   *Money `json:"old_price"`
+	NewPrice   *Money `json:"new_price"`
+	ScheduleID int    `json:"schedule_id"`
+	Reason     string `json:"reason"`
+}
+
+// PriceHistoryEntry 商品在某个币种下的一段有效价格，Price 为空表示该期间没有该币种价格，
+// EffectiveTo 为空表示当前仍然有效
+type PriceHistoryEntry struct {
+	Currency      string     `json:"currency"`
+	Price         *Money     `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+	Revision      int        `json:"revision"`
+	Action        string     `json:"action"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// PriceTier 数量阶梯价格：购买数量达到 MinQuantity 时的单价。
+// CustomerGroup 为空表示适用于所有客户，否则只适用于该客户分组
+type PriceTier struct {
+	ID            int       `json:"id"`
+	CustomerGroup string    `json:"customer_group"`
+	MinQuantity   int       `json:"min_quantity" binding:"required,min=1"`
+	Price         Money     `json:"price"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PriceTiersRequest 整体替换商品的阶梯价格
+type PriceTiersRequest struct {
+	Tiers []PriceTier `json:"tiers" binding:"dive"`
+}
+
+// PriceQuote 指定数量和客户下商品的实际单价
+type PriceQuote struct {
+	ProductID     int    `json:"product_id"`
+	Quantity      int    `json:"quantity"`
+	CustomerGroup string `json:"customer_group"`
+	// ListPrice 该币种下的标价，UnitPrice 为应用阶梯价格或促销后的单价
+	ListPrice Money `json:"list_price"`
+	UnitPrice Money `json:"unit_price"`
+	// Total 为 UnitPrice 乘以扣除赠送件数后的数量
+	Total Money `json:"total"`
+	// AppliedTier 生效的阶梯价格，标价或促销价最低时为空
+	AppliedTier *PriceTier `json:"applied_tier"`
+	// Promotion 促销价低于阶梯价格时生效的促销
+	Promotion *PromotionPrice `json:"promotion,omitempty"`
+	// AppliedOffer 生效的买赠促销，FreeQuantity 为赠送件数
+	AppliedOffer *PromotionOffer      `json:"applied_offer,omitempty"`
+	FreeQuantity int                  `json:"free_quantity"`
+	ExchangeRate *AppliedExchangeRate `json:"exchange_rate,omitempty"`
+	QuotedAt     time.Time            `json:"quoted_at"`
+}
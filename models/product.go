@@ -8,21 +8,45 @@ type Category struct {
 	ID          int       `json:"id"`
 	Name        string    `json:"name" binding:"required"`
 	Description string    `json:"description"`
+	SKUPrefix   string    `json:"sku_prefix"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// 商品生命周期状态，只有已发布的商品对公开接口可见
+const (
+	ProductStatusDraft     = "draft"
+	ProductStatusScheduled = "scheduled"
+	ProductStatusPublished = "published"
+	ProductStatusArchived  = "archived"
+)
+
 type Product struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name" binding:"required"`
-	Description string    `json:"description" binding:"required"`
-	Price       float64   `json:"price" binding:"required"`
-	Stock       int       `json:"stock" binding:"required"`
-	CategoryID  int       `json:"category_id" binding:"required"`
-	SKU         string    `json:"sku"`
-	ImageURL    string    `json:"image_url"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int    `json:"id"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description" binding:"required"`
+	Price       Money  `json:"price"`
+	Stock       int    `json:"stock" binding:"required"`
+	CategoryID  int    `json:"category_id" binding:"required"`
+	SKU         string `json:"sku"`
+	// GTIN 商品条码，保存为补零后的 GTIN-14
+	GTIN     string `json:"gtin"`
+	ImageURL string `json:"image_url"`
+	// Weight、Dimensions 包装重量和尺寸，按录入时的单位保存，未设置时为 null
+	Weight     Weight     `json:"weight"`
+	Dimensions Dimensions `json:"dimensions"`
+	Status     string     `json:"status"`
+	// PublishAt 定时发布的时间，仅 scheduled 状态有值；PublishedAt 最近一次发布的时间
+	PublishAt   *time.Time `json:"publish_at"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ProductStatusRequest 修改商品状态，status 为 scheduled 时 publish_at 必填且须晚于当前时间
+type ProductStatusRequest struct {
+	Status    string     `json:"status" binding:"required,oneof=draft scheduled published archived"`
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 type ProductDetail struct {
@@ -30,6 +54,20 @@ type ProductDetail struct {
 	CategoryName string             `json:"category_name"`
 	Attributes   []ProductAttribute `json:"attributes,omitempty"`
 	Images       []ProductImage     `json:"images,omitempty"`
+	// Prices 基础币种以外的价格表
+	Prices []Money `json:"prices,omitempty"`
+	// DisplayPrice 按请求币种展示的价格，没有该币种价格时按汇率换算
+	DisplayPrice *Money `json:"display_price,omitempty"`
+	// ExchangeRate 展示价格经过汇率换算时使用的汇率
+	ExchangeRate *AppliedExchangeRate `json:"exchange_rate,omitempty"`
+	// Promotion 展示价格（未指定币种时为基础价格）上生效的促销
+	Promotion *PromotionPrice `json:"promotion,omitempty"`
+}
+
+// DeletedProduct 回收站中的商品
+type DeletedProduct struct {
+	ProductDetail
+	DeletedAt time.Time `json:"deleted_at"`
 }
 
 type ProductAttribute struct {
@@ -38,6 +76,11 @@ type ProductAttribute struct {
 	Value string `json:"value" binding:"required"`
 }
 
+// ProductAttributesRequest 整体替换商品的属性集合，空数组表示删除全部属性
+type ProductAttributesRequest struct {
+	Attributes []ProductAttribute `json:"attributes" binding:"required,dive"`
+}
+
 type ProductImage struct {
 	ID        int    `json:"id"`
 	ImageURL  string `json:"image_url" binding:"required"`
@@ -45,15 +88,34 @@ type ProductImage struct {
 }
 
 type ProductFilter struct {
-	CategoryID int     `form:"category_id"`
-	MinPrice   float64 `form:"min_price"`
-	MaxPrice   float64 `form:"max_price"`
-	Search     string  `form:"search"`
+	CategoryID int `form:"category_id"`
+	// MinPrice、MaxPrice 为 Currency 币种的十进制金额，Currency 为空时使用默认币种
+	MinPrice string `form:"min_price"`
+	MaxPrice string `form:"max_price"`
+	Currency string `form:"currency"`
+	Search   string `form:"search"`
+	// Status 按状态过滤，all 表示全部状态；非管理员只能查询已发布的商品
+	Status string `form:"status" binding:"omitempty,oneof=draft scheduled published archived all"`
+	// MinWeight、MaxWeight 按 WeightUnit 比较，未指定单位时为克；MaxLength、MaxWidth、MaxHeight 按 DimensionUnit 比较，
+	// 未指定单位时为毫米。指定单位时返回的重量和尺寸也换算为该单位，未设置重量或尺寸的商品不参与对应的过滤
+	MinWeight     float64 `form:"min_weight" binding:"gte=0"`
+	MaxWeight     float64 `form:"max_weight" binding:"gte=0"`
+	WeightUnit    string  `form:"weight_unit"`
+	MaxLength     float64 `form:"max_length" binding:"gte=0"`
+	MaxWidth      float64 `form:"max_width" binding:"gte=0"`
+	MaxHeight     float64 `form:"max_height" binding:"gte=0"`
+	DimensionUnit string  `form:"dimension_unit"`
+	// Attributes 属性过滤，键为属性名，同一属性的多个值之间为“或”
+	Attributes map[string][]string `form:"-"`
+	// AttributeRanges 数值属性的范围过滤，键为属性名，unit 类型的属性按定义的单位比较
+	AttributeRanges map[string]AttributeRange `form:"-"`
 }
 
 type Pagination struct {
 	Page     int `form:"page" binding:"min=1"`
 	PageSize int `form:"page_size" binding:"min=1,max=100"`
+	// Cursor 游标分页，非空时忽略 Page
+	Cursor string `form:"cursor"`
 }
 
 type ProductResponse struct {
@@ -62,4 +124,54 @@ type ProductResponse struct {
 	Page      int             `json:"page"`
 	PageSize  int             `json:"page_size"`
 	TotalPage int             `json:"total_page"`
+	// NextCursor 下一页的游标，没有更多数据时为空
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Facets     *ProductFacets `json:"facets,omitempty"`
+	// SearchTruncated 全文搜索命中数超过 SEARCH_MAX_RESULTS，Total 及结果只包含相关度最高的部分
+	SearchTruncated bool `json:"search_truncated,omitempty"`
+	// IgnoredTerms 未参与搜索的词，MySQL 全文索引忽略少于 3 个字符的词
+	IgnoredTerms []string `json:"ignored_terms,omitempty"`
+}
+
+// ProductBatchRequest 按ID批量获取商品
+type ProductBatchRequest struct {
+	IDs []int `json:"ids" binding:"required,min=1"`
+}
+
+// ProductBatchResponse 批量获取结果，商品按请求中的ID顺序排列，Missing 为不存在或已删除的ID
+type ProductBatchResponse struct {
+	Products []ProductDetail `json:"products"`
+	Missing  []int           `json:"missing"`
+}
+
+// ProductFacets 当前结果集的分面统计
+type ProductFacets struct {
+	Attributes []AttributeFacet   `json:"attributes"`
+	Categories []CategoryFacet    `json:"categories"`
+	Prices     []PriceBucketFacet `json:"prices"`
+}
+
+type AttributeFacet struct {
+	Name   string       `json:"name"`
+	Values []FacetValue `json:"values"`
+}
+
+type FacetValue struct {
+	Value    string `json:"value"`
+	Count    int    `json:"count"`
+	Selected bool   `json:"selected"`
+}
+
+type CategoryFacet struct {
+	CategoryID int    `json:"category_id"`
+	Name       string `json:"name"`
+	Count      int    `json:"count"`
+	Selected   bool   `json:"selected"`
+}
+
+// PriceBucketFacet 价格区间 [Min, Max)，Max 为空表示没有上限
+type PriceBucketFacet struct {
+	Min   Money  `json:"min"`
+	Max   *Money `json:"max"`
+	Count int    `json:"count"`
 }
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 促销适用范围
+const (
+	PromotionScopeAll       = "all"
+	PromotionScopeCategory  = "category"
+	PromotionScopeProduct   = "product"
+	PromotionScopeAttribute = "attribute"
+)
+
+// 促销优惠方式
+const (
+	PromotionPercent = "percent"
+	PromotionFixed   = "fixed"
+	// PromotionBuyXGetY 买 BuyQuantity 件送 FreeQuantity 件，不改变单价，在报价时按数量计算
+	PromotionBuyXGetY = "buy_x_get_y"
+)
+
+// 促销状态，由调度任务按生效时间切换，切换时发送 promotion_started/promotion_ended 事件
+const (
+	PromotionPending = "pending"
+	PromotionRunning = "running"
+	PromotionEnded   = "ended"
+)
+
+// PromotionScope 促销适用的商品。category 匹配任一分类，product 匹配任一商品ID或SKU，
+// attribute 匹配任一属性名和值（不区分大小写）
+type PromotionScope struct {
+	Type        string             `json:"type" binding:"required,oneof=all category product attribute"`
+	CategoryIDs []int              `json:"category_ids,omitempty"`
+	ProductIDs  []int              `json:"product_ids,omitempty"`
+	SKUs        []string           `json:"skus,omitempty"`
+	Attributes  []ProductAttribute `json:"attributes,omitempty"`
+}
+
+// Promotion 目录级促销规则，在 [StartsAt, EndsAt) 期间对适用商品生效，时间为空表示不限。
+// 多个促销同时适用时按 Priority 从高到低取第一个；它可叠加时再依次叠加其余可叠加的促销
+type Promotion struct {
+	ID           int            `json:"id"`
+	Name         string         `json:"name" binding:"required"`
+	Description  string         `json:"description"`
+	Scope        PromotionScope `json:"scope"`
+	DiscountType string         `json:"discount_type" binding:"required,oneof=percent fixed buy_x_get_y"`
+	// PercentOff 折扣百分比，最多两位小数，如 20 表示减 20%
+	PercentOff json.Number `json:"percent_off,omitempty"`
+	// AmountOff 立减金额，商品价格为其他币种时按汇率换算
+	AmountOff    *Money     `json:"amount_off,omitempty"`
+	BuyQuantity  int        `json:"buy_quantity,omitempty"`
+	FreeQuantity int        `json:"free_quantity,omitempty"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Priority     int        `json:"priority"`
+	Stackable    bool       `json:"stackable"`
+	Enabled      bool       `json:"enabled"`
+	State        string     `json:"state"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// AppliedPromotion 商品价格上实际生效的一个促销及其优惠金额
+type AppliedPromotion struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Discount Money  `json:"discount"`
+}
+
+// PromotionOffer 商品适用的买赠促销
+type PromotionOffer struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	BuyQuantity  int    `json:"buy_quantity"`
+	FreeQuantity int    `json:"free_quantity"`
+}
+
+// PromotionPrice 促销前后的价格，Applied 按应用顺序排列
+type PromotionPrice struct {
+	OriginalPrice   Money              `json:"original_price"`
+	DiscountedPrice Money              `json:"discounted_price"`
+	Applied         []AppliedPromotion `json:"applied"`
+	Offers          []PromotionOffer   `json:"offers,omitempty"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// 商品修订动作
+const (
+	RevisionActionBaseline        = "baseline"
+	RevisionActionCreate          = "create"
+	RevisionActionUpdate          = "update"
+	RevisionActionDelete          = "delete"
+	RevisionActionUndelete        = "undelete"
+	RevisionActionAddImage        = "add_image"
+	RevisionActionUpdateImage     = "update_image"
+	RevisionActionRemoveImage     = "remove_image"
+	RevisionActionAddAttribute    = "add_attribute"
+	RevisionActionUpdateAttribute = "update_attribute"
+	RevisionActionRemoveAttribute = "remove_attribute"
+	RevisionActionRestoreRevision = "restore_revision"
+	RevisionActionSetPrice        = "set_price"
+	RevisionActionRemovePrice     = "remove_price"
+	RevisionActionScheduleStart   = "schedule_start"
+	RevisionActionScheduleEnd     = "schedule_end"
+	RevisionActionPublish         = "publish"
+	RevisionActionArchive         = "archive"
+	RevisionActionChangeStatus    = "change_status"
+	// RevisionActionNormalizeAttributes 按分类属性定义规范化已有属性
+	RevisionActionNormalizeAttributes = "normalize_attributes"
+	// RevisionActionReplaceAttributes 整体替换商品的属性集合
+	RevisionActionReplaceAttributes = "replace_attributes"
+)
+
+// FieldChange 两个修订之间单个字段的变化
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// ProductRevision 商品修订记录，保存变更后的完整快照
+type ProductRevision struct {
+	ID        int            `json:"id"`
+	ProductID int            `json:"product_id"`
+	Revision  int            `json:"revision"`
+	Action    string         `json:"action"`
+	ActorID   int            `json:"actor_id"`
+	Snapshot  *ProductDetail `json:"snapshot,omitempty"`
+	Diff      []FieldChange  `json:"diff"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// RevisionDiff 任意两个修订之间的差异
+type RevisionDiff struct {
+	ProductID    int           `json:"product_id"`
+	FromRevision int           `json:"from_revision"`
+	ToRevision   int           `json:"to_revision"`
+	Changes      []FieldChange `json:"changes"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// 搜索建议类型
+const (
+	SuggestionProduct   = "product"
+	SuggestionCategory  = "category"
+	SuggestionAttribute = "attribute"
+)
+
+// Suggestion 搜索框自动补全的一条建议
+type Suggestion struct {
+	Text      string  `json:"text"`
+	Type      string  `json:"type"`
+	ProductID int     `json:"product_id,omitempty"`
+	Attribute string  `json:"attribute,omitempty"`
+	Count     int     `json:"count"`
+	Score     float64 `json:"score"`
+}
+
+// SearchSynonym 搜索同义词。单向时搜索 Term 也会匹配 Synonyms，
+// 双向时 Term 与 Synonyms 中的任意词互为同义词
+type SearchSynonym struct {
+	ID            int       `json:"id"`
+	Term          string    `json:"term" binding:"required"`
+	Synonyms      []string  `json:"synonyms" binding:"required,min=1,dive,required"`
+	Bidirectional bool      `json:"bidirectional"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SearchStopWord 搜索时忽略的停用词
+type SearchStopWord struct {
+	Word      string    `json:"word" binding:"required"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,258 @@
+package promotions
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"product-service/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Item 参与促销计算的商品，只有存在属性范围的促销时才需要 Attributes
+type Item struct {
+	ProductID  int
+	CategoryID int
+	SKU        string
+	Attributes []models.ProductAttribute
+}
+
+// Converter 将金额按汇率换算为另一币种
+type Converter func(m models.Money, to string) (models.Money, bool)
+
+// rule 预先整理好匹配条件的促销
+type rule struct {
+	models.Promotion
+	// percent 折扣的万分比
+	percent    int64
+	categories map[int]bool
+	products   map[int]bool
+	skus       map[string]bool
+	attributes map[string]bool
+}
+
+// Engine 进程内的促销规则，按优先级从高到低排列
+type Engine struct {
+	mu              sync.RWMutex
+	rules           []rule
+	needsAttributes bool
+}
+
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Load 替换全部促销规则，未启用和无效的规则忽略
+func (e *Engine) Load(promotions []models.Promotion) {
+	rules := make([]rule, 0, len(promotions))
+	needsAttributes := false
+	for _, p := range promotions {
+		if !p.Enabled {
+			continue
+		}
+		r := rule{Promotion: p}
+		if p.DiscountType == models.PromotionPercent {
+			percent, err := ParsePercent(p.PercentOff)
+			if err != nil {
+				log.Printf("Ignoring promotion %d: %v", p.ID, err)
+				continue
+			}
+			r.percent = percent
+		}
+		switch p.Scope.Type {
+		case models.PromotionScopeCategory:
+			r.categories = map[int]bool{}
+			for _, id := range p.Scope.CategoryIDs {
+				r.categories[id] = true
+			}
+		case models.PromotionScopeProduct:
+			r.products = map[int]bool{}
+			for _, id := range p.Scope.ProductIDs {
+				r.products[id] = true
+			}
+			r.skus = map[string]bool{}
+			for _, sku := range p.Scope.SKUs {
+				r.skus[strings.ToLower(sku)] = true
+			}
+		case models.PromotionScopeAttribute:
+			r.attributes = map[string]bool{}
+			for _, attr := range p.Scope.Attributes {
+				r.attributes[attributeKey(attr)] = true
+			}
+			needsAttributes = true
+		}
+		rules = append(rules, r)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+	e.needsAttributes = needsAttributes
+}
+
+// NeedsAttributes 是否存在按属性匹配的促销
+func (e *Engine) NeedsAttributes() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.needsAttributes
+}
+
+// Apply 计算商品在 now 时刻的促销价格，没有适用的促销时返回 nil。
+// 按优先级取第一个可计算的价格促销；它可叠加时，再依次叠加其余可叠加的价格促销，
+// 每个折扣都以前一步的结果为基数，价格最低为零。买赠促销只列在 Offers 中
+func (e *Engine) Apply(item Item, price models.Money, now time.Time, convert Converter) *models.PromotionPrice {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	current := price.Amount
+	var first *rule
+	var applied []models.AppliedPromotion
+	var offers []models.PromotionOffer
+	for i := range e.rules {
+		r := &e.rules[i]
+		if !r.activeAt(now) || !r.matches(item) {
+			continue
+		}
+		if r.DiscountType == models.PromotionBuyXGetY {
+			offers = append(offers, models.PromotionOffer{
+				ID: r.ID, Name: r.Name, BuyQuantity: r.BuyQuantity, FreeQuantity: r.FreeQuantity,
+			})
+			continue
+		}
+		if first != nil && (!first.Stackable || !r.Stackable) {
+			continue
+		}
+
+		discount, ok := r.discount(current, price.Currency, convert)
+		if !ok {
+			continue
+		}
+		if first == nil {
+			first = r
+		}
+		current -= discount
+		applied = append(applied, models.AppliedPromotion{
+			ID: r.ID, Name: r.Name, Discount: models.Money{Amount: discount, Currency: price.Currency},
+		})
+	}
+
+	if len(applied) == 0 && len(offers) == 0 {
+		return nil
+	}
+	if applied == nil {
+		applied = []models.AppliedPromotion{}
+	}
+	return &models.PromotionPrice{
+		OriginalPrice:   price,
+		DiscountedPrice: models.Money{Amount: current, Currency: price.Currency},
+		Applied:         applied,
+		Offers:          offers,
+	}
+}
+
+// BestOffer 返回购买 quantity 件时赠送件数最多的买赠促销及赠送件数，
+// 赠送件数相同时取靠前（优先级高）的促销
+func BestOffer(offers []models.PromotionOffer, quantity int) (*models.PromotionOffer, int) {
+	var best *models.PromotionOffer
+	bestFree := 0
+	for i := range offers {
+		offer := &offers[i]
+		group := offer.BuyQuantity + offer.FreeQuantity
+		if group <= 0 {
+			continue
+		}
+		free := quantity / group * offer.FreeQuantity
+		if free > bestFree {
+			best, bestFree = offer, free
+		}
+	}
+	return best, bestFree
+}
+
+// ParsePercent 将折扣百分比解析为万分比，最多两位小数，取值范围 (0, 100]
+func ParsePercent(value json.Number) (int64, error) {
+	intPart, fracPart, _ := strings.Cut(strings.TrimSpace(string(value)), ".")
+	fracPart = strings.TrimRight(fracPart, "0")
+	if intPart == "" || len(intPart) > 3 || len(fracPart) > 2 || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, errors.New("percent_off must be a number with at most 2 decimal places")
+	}
+	percent, _ := strconv.ParseInt(intPart+fracPart+strings.Repeat("0", 2-len(fracPart)), 10, 64)
+	if percent <= 0 || percent > 10000 {
+		return 0, errors.New("percent_off must be greater than 0 and at most 100")
+	}
+	return percent, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *rule) activeAt(now time.Time) bool {
+	if r.StartsAt != nil && now.Before(*r.StartsAt) {
+		return false
+	}
+	return r.EndsAt == nil || now.Before(*r.EndsAt)
+}
+
+func (r *rule) matches(item Item) bool {
+	switch r.Scope.Type {
+	case models.PromotionScopeAll:
+		return true
+	case models.PromotionScopeCategory:
+		return r.categories[item.CategoryID]
+	case models.PromotionScopeProduct:
+		return r.products[item.ProductID] || (item.SKU != "" && r.skus[strings.ToLower(item.SKU)])
+	case models.PromotionScopeAttribute:
+		for _, attr := range item.Attributes {
+			if r.attributes[attributeKey(attr)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// discount 计算以 amount 为基数的优惠金额，不超过 amount；立减金额无法换算为 currency 时返回 false
+func (r *rule) discount(amount int64, currency string, convert Converter) (int64, bool) {
+	var discount int64
+	switch r.DiscountType {
+	case models.PromotionPercent:
+		// 分两段计算避免溢出，四舍五入到最小货币单位
+		discount = amount/10000*r.percent + (amount%10000*r.percent+5000)/10000
+	case models.PromotionFixed:
+		if r.AmountOff == nil {
+			return 0, false
+		}
+		off := *r.AmountOff
+		if off.Currency != currency {
+			converted, ok := convert(off, currency)
+			if !ok {
+				return 0, false
+			}
+			off = converted
+		}
+		discount = off.Amount
+	default:
+		return 0, false
+	}
+	return min(discount, amount), true
+}
+
+func attributeKey(attr models.ProductAttribute) string {
+	return strings.ToLower(strings.TrimSpace(attr.Name)) + "\x00" + strings.ToLower(strings.TrimSpace(attr.Value))
+}
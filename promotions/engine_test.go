@@ -0,0 +1,312 @@
+package promotions
+
+import (
+	"encoding/json"
+	"product-service/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testConvert 按 1 USD = 7 CNY 换算，其他币种没有汇率
+func testConvert(m models.Money, to string) (models.Money, bool) {
+	if m.Currency == "USD" && to == "CNY" {
+		return models.Money{Amount: m.Amount * 7, Currency: to}, true
+	}
+	return models.Money{}, false
+}
+
+func TestEngineApply(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	item := Item{
+		ProductID:  10,
+		CategoryID: 3,
+		SKU:        "ABC-001",
+		Attributes: []models.ProductAttribute{{Name: "Color", Value: "Red"}},
+	}
+	price := models.Money{Amount: 1000, Currency: "CNY"}
+	all := models.PromotionScope{Type: models.PromotionScopeAll}
+	percent := func(id int, off string, priority int, stackable bool) models.Promotion {
+		return models.Promotion{
+			ID: id, Scope: all, DiscountType: models.PromotionPercent, PercentOff: json.Number(off),
+			Priority: priority, Stackable: stackable, Enabled: true,
+		}
+	}
+	fixed := func(id int, off models.Money, priority int, stackable bool) models.Promotion {
+		return models.Promotion{
+			ID: id, Scope: all, DiscountType: models.PromotionFixed, AmountOff: &off,
+			Priority: priority, Stackable: stackable, Enabled: true,
+		}
+	}
+	withScope := func(p models.Promotion, scope models.PromotionScope) models.Promotion {
+		p.Scope = scope
+		return p
+	}
+	withWindow := func(p models.Promotion, startsAt, endsAt *time.Time) models.Promotion {
+		p.StartsAt, p.EndsAt = startsAt, endsAt
+		return p
+	}
+	buyXGetY := models.Promotion{
+		ID: 9, Scope: all, DiscountType: models.PromotionBuyXGetY, BuyQuantity: 2, FreeQuantity: 1, Enabled: true,
+	}
+	disabled := percent(1, "50", 0, false)
+	disabled.Enabled = false
+
+	tests := []struct {
+		name       string
+		promotions []models.Promotion
+		price      models.Money
+		// wantNil 没有适用的促销
+		wantNil     bool
+		wantAmount  int64
+		wantApplied []int
+		wantOffers  []int
+	}{
+		{name: "no promotions", wantNil: true},
+		{name: "disabled promotion", promotions: []models.Promotion{disabled}, wantNil: true},
+		{name: "invalid percent ignored", promotions: []models.Promotion{percent(1, "150", 0, false)}, wantNil: true},
+		{
+			name:       "percent off",
+			promotions: []models.Promotion{percent(1, "20", 0, false)},
+			wantAmount: 800, wantApplied: []int{1},
+		},
+		{
+			name:       "percent rounds half up to minor unit",
+			promotions: []models.Promotion{percent(1, "15", 0, false)},
+			price:      models.Money{Amount: 999, Currency: "CNY"},
+			// 999 * 15% = 149.85，优惠 150
+			wantAmount: 849, wantApplied: []int{1},
+		},
+		{
+			name:       "fractional percent rounds down below half",
+			promotions: []models.Promotion{percent(1, "12.5", 0, false)},
+			price:      models.Money{Amount: 1001, Currency: "CNY"},
+			// 1001 * 12.5% = 125.125，优惠 125
+			wantAmount: 876, wantApplied: []int{1},
+		},
+		{
+			name:       "fixed off",
+			promotions: []models.Promotion{fixed(1, models.Money{Amount: 150, Currency: "CNY"}, 0, false)},
+			wantAmount: 850, wantApplied: []int{1},
+		},
+		{
+			name:       "fixed off never below zero",
+			promotions: []models.Promotion{fixed(1, models.Money{Amount: 5000, Currency: "CNY"}, 0, false)},
+			wantAmount: 0, wantApplied: []int{1},
+		},
+		{
+			name:       "fixed off converted to price currency",
+			promotions: []models.Promotion{fixed(1, models.Money{Amount: 10, Currency: "USD"}, 0, false)},
+			wantAmount: 930, wantApplied: []int{1},
+		},
+		{
+			name: "fixed off without rate falls through to next promotion",
+			promotions: []models.Promotion{
+				fixed(1, models.Money{Amount: 10, Currency: "EUR"}, 5, false),
+				percent(2, "10", 1, false),
+			},
+			wantAmount: 900, wantApplied: []int{2},
+		},
+		{
+			name:       "highest priority wins",
+			promotions: []models.Promotion{percent(1, "10", 1, false), percent(2, "20", 5, false)},
+			wantAmount: 800, wantApplied: []int{2},
+		},
+		{
+			name:       "same priority prefers lower id",
+			promotions: []models.Promotion{percent(2, "20", 1, false), percent(1, "10", 1, false)},
+			wantAmount: 900, wantApplied: []int{1},
+		},
+		{
+			name: "stackable promotions compound in priority order",
+			promotions: []models.Promotion{
+				fixed(2, models.Money{Amount: 100, Currency: "CNY"}, 1, true),
+				percent(1, "10", 5, true),
+			},
+			// 1000 - 10% = 900，再减 100
+			wantAmount: 800, wantApplied: []int{1, 2},
+		},
+		{
+			name:       "non-stackable first promotion blocks the rest",
+			promotions: []models.Promotion{percent(1, "10", 5, false), percent(2, "20", 1, true)},
+			wantAmount: 900, wantApplied: []int{1},
+		},
+		{
+			name: "non-stackable promotion skipped after a stackable one",
+			promotions: []models.Promotion{
+				percent(1, "10", 5, true),
+				percent(2, "50", 3, false),
+				percent(3, "10", 1, true),
+			},
+			wantAmount: 810, wantApplied: []int{1, 3},
+		},
+		{
+			name:       "not started yet",
+			promotions: []models.Promotion{withWindow(percent(1, "20", 0, false), &future, nil)},
+			wantNil:    true,
+		},
+		{
+			name:       "ends at now is already over",
+			promotions: []models.Promotion{withWindow(percent(1, "20", 0, false), &past, &now)},
+			wantNil:    true,
+		},
+		{
+			name:       "inside window",
+			promotions: []models.Promotion{withWindow(percent(1, "20", 0, false), &now, &future)},
+			wantAmount: 800, wantApplied: []int{1},
+		},
+		{
+			name: "category scope",
+			promotions: []models.Promotion{
+				withScope(percent(1, "50", 5, false), models.PromotionScope{Type: models.PromotionScopeCategory, CategoryIDs: []int{4}}),
+				withScope(percent(2, "20", 1, false), models.PromotionScope{Type: models.PromotionScopeCategory, CategoryIDs: []int{3, 4}}),
+			},
+			wantAmount: 800, wantApplied: []int{2},
+		},
+		{
+			name: "product scope matches sku case-insensitively",
+			promotions: []models.Promotion{
+				withScope(percent(1, "20", 0, false), models.PromotionScope{Type: models.PromotionScopeProduct, SKUs: []string{"abc-001"}}),
+			},
+			wantAmount: 800, wantApplied: []int{1},
+		},
+		{
+			name: "product scope matches product id",
+			promotions: []models.Promotion{
+				withScope(percent(1, "20", 0, false), models.PromotionScope{Type: models.PromotionScopeProduct, ProductIDs: []int{10}}),
+			},
+			wantAmount: 800, wantApplied: []int{1},
+		},
+		{
+			name: "attribute scope ignores case and whitespace",
+			promotions: []models.Promotion{
+				withScope(percent(1, "20", 0, false), models.PromotionScope{
+					Type:       models.PromotionScopeAttribute,
+					Attributes: []models.ProductAttribute{{Name: " color ", Value: "RED"}},
+				}),
+			},
+			wantAmount: 800, wantApplied: []int{1},
+		},
+		{
+			name: "attribute scope without match",
+			promotions: []models.Promotion{
+				withScope(percent(1, "20", 0, false), models.PromotionScope{
+					Type:       models.PromotionScopeAttribute,
+					Attributes: []models.ProductAttribute{{Name: "Color", Value: "Blue"}},
+				}),
+			},
+			wantNil: true,
+		},
+		{
+			name:       "buy x get y only listed as offer",
+			promotions: []models.Promotion{buyXGetY},
+			wantAmount: 1000, wantApplied: []int{}, wantOffers: []int{9},
+		},
+		{
+			name:       "buy x get y does not block price promotions",
+			promotions: []models.Promotion{buyXGetY, percent(1, "20", 0, false)},
+			wantAmount: 800, wantApplied: []int{1}, wantOffers: []int{9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine()
+			engine.Load(tt.promotions)
+			p := tt.price
+			if p.Currency == "" {
+				p = price
+			}
+
+			got := engine.Apply(item, p, now, testConvert)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("Apply() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("Apply() = nil, want a promotion price")
+			}
+			if got.OriginalPrice != p {
+				t.Errorf("OriginalPrice = %+v, want %+v", got.OriginalPrice, p)
+			}
+			if want := (models.Money{Amount: tt.wantAmount, Currency: p.Currency}); got.DiscountedPrice != want {
+				t.Errorf("DiscountedPrice = %+v, want %+v", got.DiscountedPrice, want)
+			}
+
+			applied := []int{}
+			var discounts int64
+			for _, a := range got.Applied {
+				applied = append(applied, a.ID)
+				discounts += a.Discount.Amount
+			}
+			if !reflect.DeepEqual(applied, tt.wantApplied) {
+				t.Errorf("applied promotions = %v, want %v", applied, tt.wantApplied)
+			}
+			if discounts != p.Amount-tt.wantAmount {
+				t.Errorf("sum of discounts = %d, want %d", discounts, p.Amount-tt.wantAmount)
+			}
+
+			var offers []int
+			for _, o := range got.Offers {
+				offers = append(offers, o.ID)
+			}
+			if !reflect.DeepEqual(offers, tt.wantOffers) {
+				t.Errorf("offers = %v, want %v", offers, tt.wantOffers)
+			}
+		})
+	}
+}
+
+func TestEngineNeedsAttributes(t *testing.T) {
+	engine := NewEngine()
+	engine.Load([]models.Promotion{{ID: 1, Scope: models.PromotionScope{Type: models.PromotionScopeAll}, DiscountType: models.PromotionPercent, PercentOff: "10", Enabled: true}})
+	if engine.NeedsAttributes() {
+		t.Error("NeedsAttributes() = true without attribute promotions")
+	}
+	engine.Load([]models.Promotion{{ID: 1, Scope: models.PromotionScope{Type: models.PromotionScopeAttribute}, DiscountType: models.PromotionPercent, PercentOff: "10", Enabled: true}})
+	if !engine.NeedsAttributes() {
+		t.Error("NeedsAttributes() = false with an attribute promotion")
+	}
+}
+
+func TestBestOffer(t *testing.T) {
+	buy2get1 := models.PromotionOffer{ID: 1, BuyQuantity: 2, FreeQuantity: 1}
+	buy3get2 := models.PromotionOffer{ID: 2, BuyQuantity: 3, FreeQuantity: 2}
+	buy1get1 := models.PromotionOffer{ID: 3, BuyQuantity: 1, FreeQuantity: 1}
+	invalid := models.PromotionOffer{ID: 4}
+
+	tests := []struct {
+		name     string
+		offers   []models.PromotionOffer
+		quantity int
+		wantID   int
+		wantFree int
+	}{
+		{"no offers", nil, 10, 0, 0},
+		{"quantity below one group", []models.PromotionOffer{buy2get1}, 2, 0, 0},
+		{"exactly one group", []models.PromotionOffer{buy2get1}, 3, 1, 1},
+		{"partial group not counted", []models.PromotionOffer{buy2get1}, 8, 1, 2},
+		{"zero quantity", []models.PromotionOffer{buy2get1}, 0, 0, 0},
+		{"most free items wins", []models.PromotionOffer{buy2get1, buy3get2}, 10, 2, 4},
+		{"tie keeps the earlier offer", []models.PromotionOffer{buy2get1, buy1get1}, 3, 1, 1},
+		{"tie keeps the earlier offer reversed", []models.PromotionOffer{buy1get1, buy2get1}, 3, 3, 1},
+		{"empty group ignored", []models.PromotionOffer{invalid, buy2get1}, 6, 1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, free := BestOffer(tt.offers, tt.quantity)
+			gotID := 0
+			if got != nil {
+				gotID = got.ID
+			}
+			if gotID != tt.wantID || free != tt.wantFree {
+				t.Errorf("BestOffer(%v, %d) = offer %d, %d free, want offer %d, %d free",
+					tt.offers, tt.quantity, gotID, free, tt.wantID, tt.wantFree)
+			}
+		})
+	}
+}
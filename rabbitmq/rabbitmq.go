@@ -7,6 +7,10 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// BroadcastRoutingKey 副本间缓存失效通知的路由键，只有各副本的广播队列绑定该路由键，
+// 商品事件队列不会收到这类内部消息
+const BroadcastRoutingKey = "broadcast"
+
 type RabbitMQ struct {
 	Conn    *amqp.Connection
 	Channel *amqp.Channel
@@ -95,6 +99,23 @@ func (r *RabbitMQ) PublishEvent(body []byte) error {
 	)
 }
 
+// PublishBroadcast 发送只投递到各副本广播队列的内部消息，消息不持久化
+func (r *RabbitMQ) PublishBroadcast(body []byte) error {
+	msg := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+		Timestamp:   time.Now(),
+	}
+
+	return r.Channel.Publish(
+		r.Cfg.ProductExchange,
+		BroadcastRoutingKey,
+		false, // mandatory
+		false, // immediate
+		msg,
+	)
+}
+
 func (r *RabbitMQ) Close() {
 	if r.Channel != nil {
 		err := r.Channel.Close()
@@ -109,3 +130,41 @@ func (r *RabbitMQ) Close() {
 		}
 	}
 }
+
+// DeclareBroadcastQueue 在独立通道上声明当前副本专属的临时队列并绑定到商品交换机，
+// 使每个副本都能收到全部商品事件和内部广播消息（用于刷新进程内缓存），返回通道和队列名
+func (r *RabbitMQ) DeclareBroadcastQueue() (*amqp.Channel, string, error) {
+	ch, err := r.Conn.Channel()
+	if err != nil {
+		return nil, "", err
+	}
+
+	queue, err := ch.QueueDeclare(
+		"",    // 由服务器生成队列名
+		false, // durable
+		true,  // auto-delete
+		true,  // exclusive
+		false, // no-wait
+		nil,
+	)
+	if err != nil {
+		ch.Close()
+		return nil, "", err
+	}
+
+	for _, key := range []string{"", BroadcastRoutingKey} {
+		err = ch.QueueBind(
+			queue.Name,
+			key, // routing key
+			r.Cfg.ProductExchange,
+			false,
+			nil,
+		)
+		if err != nil {
+			ch.Close()
+			return nil, "", err
+		}
+	}
+
+	return ch, queue.Name, nil
+}
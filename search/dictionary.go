@@ -0,0 +1,116 @@
+package search
+
+import (
+	"product-service/models"
+	"strings"
+	"sync"
+)
+
+// Dictionary 搜索同义词和停用词，在查询时对搜索词进行处理
+type Dictionary struct {
+	mu        sync.RWMutex
+	stopWords map[string]bool
+	// synonyms 键为分词后以空格连接的词，值为包括自身在内的全部可匹配项
+	synonyms map[string][]string
+	// maxKeyTokens 同义词键的最大词数，用于限制匹配窗口
+	maxKeyTokens int
+}
+
+func NewDictionary() *Dictionary {
+	return &Dictionary{stopWords: map[string]bool{}, synonyms: map[string][]string{}}
+}
+
+// Load 替换词典内容
+func (d *Dictionary) Load(synonyms []models.SearchSynonym, stopWords []string) {
+	stop := make(map[string]bool, len(stopWords))
+	for _, word := range stopWords {
+		for _, token := range Tokenize(word) {
+			stop[token] = true
+		}
+	}
+
+	expansions := map[string][]string{}
+	maxKeyTokens := 0
+	add := func(key string, values []string) {
+		if key == "" {
+			return
+		}
+		if len(expansions[key]) == 0 {
+			expansions[key] = []string{key}
+		}
+		for _, v := range values {
+			if v != "" && !containsTerm(expansions[key], v) {
+				expansions[key] = append(expansions[key], v)
+			}
+		}
+		if n := strings.Count(key, " ") + 1; n > maxKeyTokens {
+			maxKeyTokens = n
+		}
+	}
+
+	for _, syn := range synonyms {
+		term := strings.Join(Tokenize(syn.Term), " ")
+		var values []string
+		for _, v := range syn.Synonyms {
+			values = append(values, strings.Join(Tokenize(v), " "))
+		}
+		add(term, values)
+		if syn.Bidirectional {
+			group := append([]string{term}, values...)
+			for _, v := range values {
+				add(v, group)
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopWords = stop
+	d.synonyms = expansions
+	d.maxKeyTokens = maxKeyTokens
+}
+
+// Apply 去掉停用词并展开同义词。去掉停用词后没有剩余词时保留原始搜索词；
+// 多词同义词按最长连续匹配优先
+func (d *Dictionary) Apply(q Query) Query {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	terms := make([]string, 0, len(q.Terms))
+	for _, term := range q.Terms {
+		if !d.stopWords[term] {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 && len(q.Phrases) == 0 {
+		terms = q.Terms
+	}
+
+	result := Query{Phrases: q.Phrases, Expansions: q.Expansions}
+	for i := 0; i < len(terms); {
+		matched := 0
+		for n := min(d.maxKeyTokens, len(terms)-i); n > 0; n-- {
+			key := strings.Join(terms[i:i+n], " ")
+			if group, ok := d.synonyms[key]; ok {
+				result.Expansions = append(result.Expansions, group)
+				matched = n
+				break
+			}
+		}
+		if matched == 0 {
+			result.Terms = append(result.Terms, terms[i])
+			matched = 1
+		}
+		i += matched
+	}
+	return result
+}
+
+func containsTerm(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	fieldName = iota
+	fieldDescription
+	fieldAttributes
+	fieldCategory
+	fieldCount
+)
+
+var fieldWeights = [fieldCount]float64{WeightName, WeightDescription, WeightAttributes, WeightCategory}
+
+// posting 某个词在一个商品各字段中出现的次数
+type posting [fieldCount]int
+
+// memoryDoc 已索引的商品，text 保存各字段分词后以空格拼接的文本，用于短语匹配
+type memoryDoc struct {
+	text   [fieldCount]string
+	tokens []string
+	status string
+}
+
+// MemoryEngine 进程内倒排索引，用于没有 MySQL 全文索引的部署环境
+type MemoryEngine struct {
+	mu       sync.RWMutex
+	docs     map[int]*memoryDoc
+	postings map[string]map[int]*posting
+	// sorted 为排序后的全部词，用于前缀匹配，索引变化后置空并在下次搜索时重建
+	sorted []string
+}
+
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{
+		docs:     map[int]*memoryDoc{},
+		postings: map[string]map[int]*posting{},
+	}
+}
+
+func (e *MemoryEngine) InProcess() bool {
+	return true
+}
+
+func (e *MemoryEngine) Index(doc Document) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.remove(doc.ProductID)
+
+	fields := [fieldCount]string{
+		fieldName:        doc.Name,
+		fieldDescription: doc.Description,
+		fieldAttributes:  strings.Join(doc.Attributes, " "),
+		fieldCategory:    doc.Category,
+	}
+
+	indexed := &memoryDoc{status: doc.Status}
+	seen := map[string]bool{}
+	for field, text := range fields {
+		tokens := Tokenize(text)
+		indexed.text[field] = " " + strings.Join(tokens, " ") + " "
+		for _, token := range tokens {
+			docs, ok := e.postings[token]
+			if !ok {
+				docs = map[int]*posting{}
+				e.postings[token] = docs
+				e.sorted = nil
+			}
+			p, ok := docs[doc.ProductID]
+			if !ok {
+				p = &posting{}
+				docs[doc.ProductID] = p
+			}
+			p[field]++
+			if !seen[token] {
+				seen[token] = true
+				indexed.tokens = append(indexed.tokens, token)
+			}
+		}
+	}
+
+	e.docs[doc.ProductID] = indexed
+	return nil
+}
+
+func (e *MemoryEngine) Remove(productID int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remove(productID)
+	return nil
+}
+
+func (e *MemoryEngine) remove(productID int) {
+	doc, ok := e.docs[productID]
+	if !ok {
+		return
+	}
+	for _, token := range doc.tokens {
+		delete(e.postings[token], productID)
+		if len(e.postings[token]) == 0 {
+			delete(e.postings, token)
+			e.sorted = nil
+		}
+	}
+	delete(e.docs, productID)
+}
+
+// prefixTokens 返回以 prefix 开头的全部词，调用方需持有写锁
+func (e *MemoryEngine) prefixTokens(prefix string) []string {
+	if e.sorted == nil {
+		e.sorted = make([]string, 0, len(e.postings))
+		for token := range e.postings {
+			e.sorted = append(e.sorted, token)
+		}
+		sort.Strings(e.sorted)
+	}
+
+	var tokens []string
+	for i := sort.SearchStrings(e.sorted, prefix); i < len(e.sorted) && strings.HasPrefix(e.sorted[i], prefix); i++ {
+		tokens = append(tokens, e.sorted[i])
+	}
+	return tokens
+}
+
+// Search 每个词按前缀匹配且都必须出现，短语须在同一字段中连续出现，同义词组匹配任一项即可；
+// 得分为各字段加权词频乘以逆文档频率之和
+func (e *MemoryEngine) Search(q Query, limit int) ([]Hit, error) {
+	// 前缀匹配可能需要重建排序词表，因此使用写锁
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if q.Empty() || len(e.docs) == 0 {
+		return nil, nil
+	}
+
+	var scores map[int]float64
+
+	for _, term := range q.Terms {
+		if scores = intersectScores(scores, e.termScores(term)); len(scores) == 0 {
+			return nil, nil
+		}
+	}
+
+	for _, phrase := range q.Phrases {
+		if scores = intersectScores(scores, e.phraseScores(phrase)); len(scores) == 0 {
+			return nil, nil
+		}
+	}
+
+	// 同义词组内任一项匹配即可，取最高得分
+	for _, group := range q.Expansions {
+		groupScores := map[int]float64{}
+		for _, alt := range group {
+			altScores := e.phraseScores
+			if !strings.Contains(alt, " ") {
+				altScores = e.termScores
+			}
+			for id, score := range altScores(alt) {
+				groupScores[id] = math.Max(groupScores[id], score)
+			}
+		}
+		if scores = intersectScores(scores, groupScores); len(scores) == 0 {
+			return nil, nil
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		if q.Status != "" && e.docs[id].status != q.Status {
+			continue
+		}
+		hits = append(hits, Hit{ProductID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ProductID < hits[j].ProductID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// IgnoredTerms 进程内索引不忽略任何词
+func (e *MemoryEngine) IgnoredTerms(q Query) []string {
+	return nil
+}
+
+// termScores 按前缀匹配单个词，调用方需持有写锁
+func (e *MemoryEngine) termScores(term string) map[int]float64 {
+	total := float64(len(e.docs))
+	scores := map[int]float64{}
+	for _, token := range e.prefixTokens(term) {
+		docs := e.postings[token]
+		idf := math.Log(1 + total/float64(len(docs)))
+		for id, p := range docs {
+			for field, tf := range p {
+				scores[id] += fieldWeights[field] * float64(tf) * idf
+			}
+		}
+	}
+	return scores
+}
+
+// phraseScores 匹配在同一字段中连续出现的短语
+func (e *MemoryEngine) phraseScores(phrase string) map[int]float64 {
+	scores := map[int]float64{}
+	first := strings.SplitN(phrase, " ", 2)[0]
+	for id := range e.postings[first] {
+		for field, text := range e.docs[id].text {
+			if strings.Contains(text, " "+phrase+" ") {
+				scores[id] += fieldWeights[field] * 2
+			}
+		}
+	}
+	return scores
+}
+
+// intersectScores 合并两组得分，只保留两边都出现的商品；acc 为 nil 时直接返回 next
+func intersectScores(acc, next map[int]float64) map[int]float64 {
+	if acc == nil {
+		return next
+	}
+	for id := range acc {
+		if score, ok := next[id]; ok {
+			acc[id] += score
+		} else {
+			delete(acc, id)
+		}
+	}
+	return acc
+}
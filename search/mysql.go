@@ -0,0 +1,198 @@
+package search
+
+import (
+	"product-service/database"
+	"strings"
+	"unicode/utf8"
+)
+
+// InnoDB 默认的 innodb_ft_min_token_size，更短的词不会进入全文索引
+const mysqlMinTokenLength = 3
+
+// MySQLEngine 基于 product_search_index 表 FULLTEXT 索引的搜索引擎
+type MySQLEngine struct{}
+
+func NewMySQLEngine() *MySQLEngine {
+	return &MySQLEngine{}
+}
+
+func (e *MySQLEngine) InProcess() bool {
+	return false
+}
+
+func (e *MySQLEngine) Index(doc Document) error {
+	_, err := database.DB.Exec(`
+		REPLACE INTO product_search_index (product_id, name, description, attributes, category)
+		VALUES (?, ?, ?, ?, ?)
+	`, doc.ProductID, doc.Name, doc.Description, strings.Join(doc.Attributes, " "), doc.Category)
+	return err
+}
+
+func (e *MySQLEngine) Remove(productID int) error {
+	_, err := database.DB.Exec("DELETE FROM product_search_index WHERE product_id = ?", productID)
+	return err
+}
+
+// statusFilter 按商品当前状态过滤的 JOIN 和条件，状态以 products 表为准，不依赖索引是否及时刷新
+func statusFilter(q Query) (join, where string, args []interface{}) {
+	if q.Status == "" {
+		return "", "", nil
+	}
+	return " JOIN products p ON p.id = s.product_id", " AND p.status = ?", []interface{}{q.Status}
+}
+
+// Search 使用布尔模式过滤（所有词必须出现），再按各字段加权的自然相关度排序
+func (e *MySQLEngine) Search(q Query, limit int) ([]Hit, error) {
+	var required, optional []string
+	for _, term := range q.Terms {
+		if utf8.RuneCountInString(term) < mysqlMinTokenLength {
+			continue
+		}
+		required = append(required, "+"+term+"*")
+		optional = append(optional, term+"*")
+	}
+	for _, phrase := range q.Phrases {
+		required = append(required, `+"`+phrase+`"`)
+		optional = append(optional, `"`+phrase+`"`)
+	}
+	for _, group := range q.Expansions {
+		var alternatives []string
+		for _, alt := range group {
+			switch {
+			case strings.Contains(alt, " "):
+				alternatives = append(alternatives, `"`+alt+`"`)
+			case utf8.RuneCountInString(alt) >= mysqlMinTokenLength:
+				alternatives = append(alternatives, alt+"*")
+			}
+		}
+		if len(alternatives) == 0 {
+			continue
+		}
+		required = append(required, "+("+strings.Join(alternatives, " ")+")")
+		optional = append(optional, alternatives...)
+	}
+
+	if len(required) == 0 {
+		return e.searchLike(q, limit)
+	}
+
+	filter := strings.Join(required, " ")
+	scoring := strings.Join(optional, " ")
+
+	join, where, statusArgs := statusFilter(q)
+	args := []interface{}{
+		scoring, WeightName, scoring, WeightDescription, scoring, WeightAttributes,
+		scoring, WeightCategory, filter,
+	}
+	args = append(append(args, statusArgs...), limit)
+
+	rows, err := database.DB.Query(`
+		SELECT s.product_id,
+		       MATCH(s.name) AGAINST(? IN BOOLEAN MODE) * ?
+		     + MATCH(s.description) AGAINST(? IN BOOLEAN MODE) * ?
+		     + MATCH(s.attributes) AGAINST(? IN BOOLEAN MODE) * ?
+		     + MATCH(s.category) AGAINST(? IN BOOLEAN MODE) * ? AS score
+		FROM product_search_index s`+join+`
+		WHERE MATCH(s.name, s.description, s.attributes, s.category) AGAINST(? IN BOOLEAN MODE)`+where+`
+		ORDER BY score DESC, s.product_id
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanHits(rows)
+}
+
+// IgnoredTerms 存在可用于全文索引的条件时，短于 mysqlMinTokenLength 的词被忽略；
+// 所有词都较短时退化为 LIKE 匹配，不忽略任何词
+func (e *MySQLEngine) IgnoredTerms(q Query) []string {
+	var short []string
+	usable := len(q.Phrases) > 0
+	for _, term := range q.Terms {
+		if utf8.RuneCountInString(term) < mysqlMinTokenLength {
+			short = append(short, term)
+		} else {
+			usable = true
+		}
+	}
+	for _, group := range q.Expansions {
+		dropped := true
+		for _, alt := range group {
+			if strings.Contains(alt, " ") || utf8.RuneCountInString(alt) >= mysqlMinTokenLength {
+				dropped = false
+				break
+			}
+		}
+		if dropped {
+			short = append(short, group...)
+		} else {
+			usable = true
+		}
+	}
+	if !usable {
+		return nil
+	}
+	return short
+}
+
+// searchLike 搜索词都短于全文索引最小长度时退化为 LIKE 匹配
+func (e *MySQLEngine) searchLike(q Query, limit int) ([]Hit, error) {
+	// 每个条件是一组可选项，组内任一项出现在任一字段即可
+	var groups [][]string
+	for _, term := range q.Terms {
+		groups = append(groups, []string{term})
+	}
+	groups = append(groups, q.Expansions...)
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	// 名称包含第一组第一项的结果排在前面
+	args := []interface{}{"%" + groups[0][0] + "%", WeightName}
+	var conditions []string
+	for _, group := range groups {
+		var alternatives []string
+		for _, alt := range group {
+			alternatives = append(alternatives, "s.name LIKE ? OR s.description LIKE ? OR s.attributes LIKE ? OR s.category LIKE ?")
+			pattern := "%" + alt + "%"
+			args = append(args, pattern, pattern, pattern, pattern)
+		}
+		conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	join, where, statusArgs := statusFilter(q)
+	args = append(append(args, statusArgs...), limit)
+
+	rows, err := database.DB.Query(`
+		SELECT s.product_id, IF(s.name LIKE ?, ?, 1) AS score
+		FROM product_search_index s`+join+`
+		WHERE `+strings.Join(conditions, " AND ")+where+`
+		ORDER BY score DESC, s.product_id
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanHits(rows)
+}
+
+type hitRows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+func scanHits(rows hitRows) ([]Hit, error) {
+	var hits []Hit
+	for rows.Next() {
+		var hit Hit
+		if err := rows.Scan(&hit.ProductID, &hit.Score); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 字段权重：名称高于属性、分类和描述
+const (
+	WeightName        = 3.0
+	WeightAttributes  = 1.5
+	WeightCategory    = 1.0
+	WeightDescription = 1.0
+)
+
+// Document 一个商品的可搜索内容
+type Document struct {
+	ProductID   int
+	Name        string
+	Description string
+	Category    string
+	Attributes  []string
+	// Status 商品状态，用于在截断结果前按状态过滤
+	Status string
+}
+
+// Query 解析后的搜索条件，所有词、短语和同义词组都必须匹配
+type Query struct {
+	Terms   []string
+	Phrases []string
+	// Expansions 同义词扩展后的词组，每组至少匹配其中一项，含空格的项按短语匹配
+	Expansions [][]string
+	// Status 只返回该状态的商品，为空时不限；在取前 limit 条之前过滤
+	Status string
+}
+
+// Empty 没有任何可用的搜索词
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.Expansions) == 0
+}
+
+// Hit 一条搜索结果
+type Hit struct {
+	ProductID int
+	Score     float64
+}
+
+// Engine 搜索引擎接口
+type Engine interface {
+	// Index 新增或替换商品的索引
+	Index(doc Document) error
+	// Remove 从索引中删除商品
+	Remove(productID int) error
+	// Search 按相关度从高到低返回最多 limit 条结果
+	Search(q Query, limit int) ([]Hit, error)
+	// IgnoredTerms 搜索时未参与匹配的词，如短于全文索引最小长度的词
+	IgnoredTerms(q Query) []string
+	// InProcess 索引是否保存在进程内，进程内索引需要每个副本各自根据事件刷新
+	InProcess() bool
+}
+
+// ParseQuery 解析搜索字符串，双引号包围的部分作为短语，其余按空白拆分为词
+func ParseQuery(s string) Query {
+	var q Query
+
+	parts := strings.Split(s, `"`)
+	for i, part := range parts {
+		// 奇数位置在引号内
+		if i%2 == 1 {
+			if phrase := strings.Join(Tokenize(part), " "); phrase != "" {
+				q.Phrases = append(q.Phrases, phrase)
+			}
+			continue
+		}
+		q.Terms = append(q.Terms, Tokenize(part)...)
+	}
+
+	return q
+}
+
+// Tokenize 将文本拆分为小写的词，中日韩文字每个字单独成词
+func Tokenize(s string) []string {
+	var tokens []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,252 @@
+package search
+
+import (
+	"math"
+	"product-service/models"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 建议匹配方式的基础得分：整段前缀 > 词前缀 > 拼写容错
+const (
+	suggestScorePrefix     = 3.0
+	suggestScoreWordPrefix = 2.0
+	suggestScoreFuzzy      = 1.0
+)
+
+// 不同建议类型的附加得分，商品名称优先
+var suggestTypeBoost = map[string]float64{
+	models.SuggestionProduct:   0.3,
+	models.SuggestionCategory:  0.2,
+	models.SuggestionAttribute: 0.1,
+}
+
+// 启用拼写容错的最短输入长度
+const minFuzzyLength = 3
+
+type suggestKey struct {
+	kind      string
+	attribute string
+	text      string
+}
+
+type suggestEntry struct {
+	display  string
+	products map[int]struct{}
+}
+
+// suggestTerm 可匹配的词条，每个建议在每个词的起始位置各有一条，用于匹配中间的词
+type suggestTerm struct {
+	text      string
+	key       suggestKey
+	wordStart bool
+}
+
+// Suggester 进程内的自动补全索引，包含商品名称、分类名称和属性值
+type Suggester struct {
+	mu        sync.RWMutex
+	entries   map[suggestKey]*suggestEntry
+	byProduct map[int][]suggestKey
+	// terms 按文本排序的词条，索引变化后置空并在下次查询时重建
+	terms []suggestTerm
+}
+
+func NewSuggester() *Suggester {
+	return &Suggester{
+		entries:   map[suggestKey]*suggestEntry{},
+		byProduct: map[int][]suggestKey{},
+	}
+}
+
+// normalizeSuggestText 统一为分词后以空格连接的小写文本
+func normalizeSuggestText(s string) string {
+	return strings.Join(Tokenize(s), " ")
+}
+
+// Index 新增或替换一个商品贡献的建议
+func (s *Suggester) Index(productID int, name, category string, attributes []models.ProductAttribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(productID)
+
+	add := func(kind, attribute, display string) {
+		key := suggestKey{kind: kind, attribute: strings.ToLower(attribute), text: normalizeSuggestText(display)}
+		if key.text == "" {
+			return
+		}
+		entry, ok := s.entries[key]
+		if !ok {
+			entry = &suggestEntry{display: strings.TrimSpace(display), products: map[int]struct{}{}}
+			s.entries[key] = entry
+			s.terms = nil
+		}
+		if _, ok := entry.products[productID]; ok {
+			return
+		}
+		entry.products[productID] = struct{}{}
+		s.byProduct[productID] = append(s.byProduct[productID], key)
+	}
+
+	add(models.SuggestionProduct, "", name)
+	add(models.SuggestionCategory, "", category)
+	for _, attr := range attributes {
+		add(models.SuggestionAttribute, attr.Name, attr.Value)
+	}
+}
+
+// Remove 删除商品贡献的建议，其他商品仍在使用的分类和属性值保留
+func (s *Suggester) Remove(productID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(productID)
+}
+
+func (s *Suggester) remove(productID int) {
+	for _, key := range s.byProduct[productID] {
+		entry := s.entries[key]
+		delete(entry.products, productID)
+		if len(entry.products) == 0 {
+			delete(s.entries, key)
+			s.terms = nil
+		}
+	}
+	delete(s.byProduct, productID)
+}
+
+// buildTerms 重建排序词条，调用方需持有写锁
+func (s *Suggester) buildTerms() {
+	if s.terms != nil {
+		return
+	}
+	s.terms = make([]suggestTerm, 0, len(s.entries))
+	for key := range s.entries {
+		s.terms = append(s.terms, suggestTerm{text: key.text, key: key})
+		for i := 0; i < len(key.text); i++ {
+			if key.text[i] == ' ' {
+				s.terms = append(s.terms, suggestTerm{text: key.text[i+1:], key: key, wordStart: true})
+			}
+		}
+	}
+	sort.Slice(s.terms, func(i, j int) bool { return s.terms[i].text < s.terms[j].text })
+}
+
+// Suggest 返回与输入前缀匹配的建议；前缀匹配不足 limit 条时按编辑距离补充拼写相近的结果
+func (s *Suggester) Suggest(input string, limit int) []models.Suggestion {
+	query := normalizeSuggestText(input)
+	if query == "" || limit <= 0 {
+		return nil
+	}
+
+	// 词条可能需要重建，因此使用写锁
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buildTerms()
+
+	scores := map[suggestKey]float64{}
+	setScore := func(key suggestKey, score float64) {
+		if score > scores[key] {
+			scores[key] = score
+		}
+	}
+
+	for i := sort.Search(len(s.terms), func(i int) bool { return s.terms[i].text >= query }); i < len(s.terms); i++ {
+		term := s.terms[i]
+		if !strings.HasPrefix(term.text, query) {
+			break
+		}
+		if term.wordStart {
+			setScore(term.key, suggestScoreWordPrefix)
+		} else {
+			setScore(term.key, suggestScorePrefix)
+		}
+	}
+
+	if n := utf8.RuneCountInString(query); len(scores) < limit && n >= minFuzzyLength {
+		maxEdits := 1
+		if n >= 6 {
+			maxEdits = 2
+		}
+		q := []rune(query)
+		for _, term := range s.terms {
+			if _, ok := scores[term.key]; ok {
+				continue
+			}
+			if d := prefixEditDistance(q, term.text, maxEdits); d <= maxEdits {
+				setScore(term.key, suggestScoreFuzzy-0.25*float64(d))
+			}
+		}
+	}
+
+	suggestions := make([]models.Suggestion, 0, len(scores))
+	for key, score := range scores {
+		entry := s.entries[key]
+		suggestion := models.Suggestion{
+			Text:      entry.display,
+			Type:      key.kind,
+			Attribute: key.attribute,
+			Count:     len(entry.products),
+			Score:     score + suggestTypeBoost[key.kind] + 0.1*math.Log1p(float64(len(entry.products))),
+		}
+		if key.kind == models.SuggestionProduct && len(entry.products) == 1 {
+			for id := range entry.products {
+				suggestion.ProductID = id
+			}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Text) != len(b.Text) {
+			return len(a.Text) < len(b.Text)
+		}
+		return a.Text < b.Text
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// prefixEditDistance 计算 query 与 text 任意前缀之间的最小编辑距离，超过 maxEdits 时提前返回
+func prefixEditDistance(query []rune, text string, maxEdits int) int {
+	t := []rune(text)
+	if len(t) > len(query)+maxEdits {
+		t = t[:len(query)+maxEdits]
+	}
+
+	// prev[j] 为 query[:i] 与 t[:j] 的编辑距离
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(query); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if query[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > maxEdits {
+			return rowMin
+		}
+		prev, curr = curr, prev
+	}
+
+	best := prev[0]
+	for _, d := range prev {
+		best = min(best, d)
+	}
+	return best
+}
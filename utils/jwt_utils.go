@@ -3,7 +3,9 @@ package utils
 import (
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -31,8 +33,29 @@ func GenerateToken(userID int, jwtSecret string) (string, error) {
 	return tokenString, nil
 }
 
+// TokenClaims 令牌中服务使用的声明
+type TokenClaims struct {
+	UserID int
+	// CustomerGroup 客户分组，用于分组定价，未设置时为空
+	CustomerGroup string
+}
+
+// customerGroupPattern 客户分组名称的格式
+var customerGroupPattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
 // ValidateToken 验证JWT令牌
 func ValidateToken(tokenString, jwtSecret string) (int, error) {
+	claims, err := ValidateTokenClaims(tokenString, jwtSecret)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// ValidateTokenClaims 验证JWT令牌并返回用户ID和客户分组，客户分组格式不正确时令牌无效
+func ValidateTokenClaims(tokenString, jwtSecret string) (TokenClaims, error) {
+	var result TokenClaims
+
 	// 解析令牌
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// 验证签名方法
@@ -43,37 +66,59 @@ func ValidateToken(tokenString, jwtSecret string) (int, error) {
 	})
 
 	if err != nil {
-		return 0, err
+		return result, err
 	}
 
 	// 验证令牌
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// 提取用户ID
-		userID, ok := claims["user_id"]
-		if !ok {
-			return 0, errors.New("user_id claim is missing")
-		}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return result, errors.New("invalid token")
+	}
 
-		// 将用户ID转换为整数
-		var id int
-		switch v := userID.(type) {
-		case float64:
-			id = int(v)
-		case int:
-			id = v
-		case string:
-			id, err = strconv.Atoi(v)
-			if err != nil {
-				return 0, errors.New("invalid user_id format")
-			}
-		default:
-			return 0, errors.New("invalid user_id type")
+	// 提取用户ID
+	userID, ok := claims["user_id"]
+	if !ok {
+		return result, errors.New("user_id claim is missing")
+	}
+
+	// 将用户ID转换为整数
+	switch v := userID.(type) {
+	case float64:
+		result.UserID = int(v)
+	case int:
+		result.UserID = v
+	case string:
+		result.UserID, err = strconv.Atoi(v)
+		if err != nil {
+			return result, errors.New("invalid user_id format")
 		}
+	default:
+		return result, errors.New("invalid user_id type")
+	}
 
-		return id, nil
+	// 提取客户分组
+	if group, ok := claims["customer_group"]; ok && group != nil {
+		name, ok := group.(string)
+		if !ok {
+			return result, errors.New("invalid customer_group type")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !customerGroupPattern.MatchString(name) {
+			return result, errors.New("invalid customer_group format")
+		}
+		result.CustomerGroup = name
 	}
 
-	return 0, errors.New("invalid token")
+	return result, nil
+}
+
+// NormalizeCustomerGroup 转为小写并校验客户分组名称
+func NormalizeCustomerGroup(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !customerGroupPattern.MatchString(name) {
+		return "", errors.New("customer group must be 1-64 characters of a-z, 0-9, _ or -")
+	}
+	return name, nil
 }
 
 // GetUserIDFromToken 从令牌中提取用户ID（不验证令牌）
@@ -1,20 +1,33 @@
 package utils
 
 import (
+	"fmt"
 	"product-service/models"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-func ParsePagination(c *gin.Context) models.Pagination {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+// 每页数量上限
+const MaxPageSize = 100
+
+// ParsePagination 解析并校验分页参数
+func ParsePagination(c *gin.Context) (models.Pagination, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return models.Pagination{}, fmt.Errorf("page must be a positive integer")
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 || pageSize > MaxPageSize {
+		return models.Pagination{}, fmt.Errorf("page_size must be an integer between 1 and %d", MaxPageSize)
+	}
 
 	return models.Pagination{
 		Page:     page,
 		PageSize: pageSize,
-	}
+		Cursor:   c.Query("cursor"),
+	}, nil
 }
 
 func CalculateTotalPages(total int, pageSize int) int {
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+	xlsxWorkbookHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="`
+
+	xlsxWorkbookTail = `" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+	xlsxSheetHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	xlsxSheetTail = `</sheetData></worksheet>`
+)
+
+// XLSXWriter 流式写出只有一个工作表的 XLSX 文件，行数据直接写入 zip 流，不在内存中累积
+type XLSXWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	err   error
+}
+
+// NewXLSXWriter 创建 XLSX 写入器，sheetName 为工作表名称
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	zw := zip.NewWriter(w)
+
+	var name strings.Builder
+	_ = xml.EscapeText(&name, []byte(sheetName))
+
+	parts := []struct {
+		path    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", xlsxWorkbookHead + name.String() + xlsxWorkbookTail},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// 工作表必须最后创建，之后的写入都属于该文件
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &XLSXWriter{zip: zw, sheet: bufio.NewWriter(sheet)}
+	_, xw.err = xw.sheet.WriteString(xlsxSheetHead)
+	return xw, xw.err
+}
+
+// WriteRow 写入一行，数值类型写为数字单元格，其余写为文本
+func (w *XLSXWriter) WriteRow(values []interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	w.sheet.WriteString("<row>")
+	for _, value := range values {
+		switch v := value.(type) {
+		case int:
+			w.writeNumber(strconv.Itoa(v))
+		case int64:
+			w.writeNumber(strconv.FormatInt(v, 10))
+		case float64:
+			w.writeNumber(strconv.FormatFloat(v, 'f', -1, 64))
+		case string:
+			w.writeString(v)
+		case nil:
+			w.sheet.WriteString("<c/>")
+		default:
+			w.writeString(fmt.Sprint(v))
+		}
+	}
+	_, w.err = w.sheet.WriteString("</row>")
+	return w.err
+}
+
+func (w *XLSXWriter) writeNumber(v string) {
+	w.sheet.WriteString("<c><v>")
+	w.sheet.WriteString(v)
+	w.sheet.WriteString("</v></c>")
+}
+
+func (w *XLSXWriter) writeString(v string) {
+	w.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	_ = xml.EscapeText(w.sheet, []byte(stripInvalidXMLChars(v)))
+	w.sheet.WriteString("</t></is></c>")
+}
+
+// Flush 将缓冲的数据写出到底层流
+func (w *XLSXWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.sheet.Flush(); w.err != nil {
+		return w.err
+	}
+	w.err = w.zip.Flush()
+	return w.err
+}
+
+// Close 结束工作表并写出 zip 目录
+func (w *XLSXWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := w.sheet.WriteString(xlsxSheetTail); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// stripInvalidXMLChars 去除 XML 1.0 不允许的控制字符
+func stripInvalidXMLChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 {
+			return r
+		}
+		return -1
+	}, s)
+}